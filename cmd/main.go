@@ -1,7 +1,10 @@
 package main
 
 import (
+	"S3Storage/internal/auth"
+	"S3Storage/internal/config"
 	storage "S3Storage/internal/storage"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,12 +16,81 @@ func redirectToHttps(w http.ResponseWriter, r *http.Request) {
 }
 
 func main() {
-	http.HandleFunc("/create-user", storage.Create)
-	http.HandleFunc("/delete-user", storage.Delete)
-	http.HandleFunc("/upload-file", storage.UploadFileToS3)
-	http.HandleFunc("/download-file", storage.DownloadFileFromS3)
-	http.HandleFunc("/delete-file", storage.DeleteFileFromS3)
-	http.HandleFunc("/list-files", storage.ListFilesInBucket)
+	cfg, err := config.Load("configs")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := storage.Init(cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	// S3 issue-token <login> <name> — выпуск токена без HTTP, например
+	// первого токена нового пользователя.
+	if len(os.Args) > 1 && os.Args[1] == "issue-token" {
+		if len(os.Args) != 4 {
+			log.Fatal("usage: S3 issue-token <login> <name>")
+		}
+		token, err := storage.IssueToken(context.Background(), os.Args[2], os.Args[3])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(token)
+		return
+	}
+
+	// S3 set-role <login> <role> — назначение роли без HTTP, например
+	// первого администратора.
+	if len(os.Args) > 1 && os.Args[1] == "set-role" {
+		if len(os.Args) != 4 {
+			log.Fatal("usage: S3 set-role <login> <role>")
+		}
+		if err := storage.SetRole(context.Background(), os.Args[2], os.Args[3]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Все обработчики, кроме редиректа и публичных ссылок, доступны только
+	// с токеном; каждый объявляет scope, который нужен для обращения к
+	// нему. Scope должен быть разрешён и токеном, и ролью пользователя.
+	authn := storage.Authenticator()
+	handle := func(pattern, scope string, handler http.HandlerFunc) {
+		http.Handle(pattern, auth.Middleware(authn, auth.Require(scope, handler)))
+	}
+
+	handle("/create-user", auth.ScopeAdmin, storage.Create)
+	handle("/delete-user", auth.ScopeAdmin, storage.Delete)
+	handle("/user-role", auth.ScopeAdmin, storage.UserRole)
+	handle("/user-quota", auth.ScopeAdmin, storage.UserQuota)
+	handle("/credentials-cache", auth.ScopeAdmin, storage.CredentialsCache)
+	handle("/upload-file", auth.ScopeWrite, storage.UploadFileToS3)
+	handle("/uploads", auth.ScopeWrite, storage.ResumableUpload)
+	handle("/uploads/", auth.ScopeWrite, storage.ResumableUpload)
+	handle("/download-file", auth.ScopeRead, storage.DownloadFileFromS3)
+	handle("/file-info", auth.ScopeRead, storage.FileInfo)
+	handle("/file-acl", auth.ScopeRead, storage.FileACL)
+	handle("/set-file-acl", auth.ScopeWrite, storage.SetFileACL)
+	handle("/delete-file", auth.ScopeDelete, storage.DeleteFileFromS3)
+	handle("/delete-files", auth.ScopeDelete, storage.DeleteFiles)
+	handle("/copy-file", auth.ScopeWrite, storage.CopyFile)
+	handle("/move-file", auth.ScopeDelete, storage.MoveFile)
+	handle("/rename-file", auth.ScopeDelete, storage.RenameFile)
+	handle("/list-files", auth.ScopeRead, storage.ListFilesInBucket)
+	handle("/usage", auth.ScopeRead, storage.Usage)
+	handle("/create-bucket", auth.ScopeWrite, storage.CreateBucket)
+	handle("/list-buckets", auth.ScopeRead, storage.ListBuckets)
+	handle("/delete-bucket", auth.ScopeDelete, storage.DeleteBucket)
+	handle("/bucket-info", auth.ScopeRead, storage.GetBucketInfo)
+	handle("/tokens", auth.ScopeTokens, storage.Tokens)
+	handle("/access-keys", auth.ScopeTokens, storage.AccessKeys)
+	handle("/grants", auth.ScopeShare, storage.Grants)
+	handle("/shares", auth.ScopeShare, storage.Shares)
+	handle("/presign-download", auth.ScopeRead, storage.PresignDownload)
+	handle("/presign-upload", auth.ScopeWrite, storage.PresignUpload)
+	handle("/presign-post", auth.ScopeWrite, storage.PresignPost)
+
+	// Публичные ссылки открываются без токена.
+	http.HandleFunc("/s/", storage.ServeShare)
 
 	log.Println("http/https server start listening on port", 8442, 8443)
 
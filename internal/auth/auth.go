@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Способы аутентификации.
+const (
+	MethodToken = "token"
+	MethodJWT   = "jwt"
+)
+
+// Principal — пользователь, чей токен прошёл проверку.
+type Principal struct {
+	Login   string
+	Role    Role
+	Method  string
+	TokenID string
+	// Scopes — разрешения из токена. nil означает отсутствие
+	// ограничений.
+	Scopes []string
+}
+
+// Can сообщает, разрешено ли действие scope и ролью, и токеном.
+func (p *Principal) Can(scope string) bool {
+	return (scope == "" || p.Role.Allows(scope)) && p.HasScope(scope)
+}
+
+// IsAdmin сообщает, что пользователь — администратор.
+func (p *Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// HasScope сообщает, разрешено ли действие scope токеном.
+func (p *Principal) HasScope(scope string) bool {
+	if p.Scopes == nil || scope == "" {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator проверяет bearer-токен. Если токен не подходит, он
+// возвращает ErrInvalidToken.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// Chain пробует аутентификаторы по очереди и возвращает первого
+// распознанного пользователя.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(ctx, token)
+		if err == nil {
+			return p, nil
+		}
+		if !errors.Is(err, ErrInvalidToken) {
+			return nil, err
+		}
+	}
+	return nil, ErrInvalidToken
+}
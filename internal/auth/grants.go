@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Permission — право, которое владелец выдаёт на свой бакет или префикс.
+type Permission string
+
+const (
+	PermRead   Permission = "read"
+	PermWrite  Permission = "write"
+	PermDelete Permission = "delete"
+)
+
+var (
+	// ErrUnknownPermission возвращается для права вне списка.
+	ErrUnknownPermission = errors.New("auth: unknown permission")
+	// ErrGrantNotFound возвращается при отзыве несуществующего права.
+	ErrGrantNotFound = errors.New("auth: grant not found")
+	// ErrGrantExists возвращается, если такое право уже выдано.
+	ErrGrantExists = errors.New("auth: grant already exists")
+)
+
+// ParsePermission проверяет название права.
+func ParsePermission(s string) (Permission, error) {
+	switch p := Permission(s); p {
+	case PermRead, PermWrite, PermDelete:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownPermission, s)
+}
+
+// Grant — право grantee на ключи с префиксом Prefix в бакете Bucket
+// пользователя Owner. Пустой префикс означает весь бакет.
+type Grant struct {
+	ID         string     `json:"id"`
+	Owner      string     `json:"owner"`
+	Bucket     string     `json:"bucket"`
+	Prefix     string     `json:"prefix"`
+	Grantee    string     `json:"grantee"`
+	Permission Permission `json:"permission"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateGrant сохраняет право g, заполняя его ID и CreatedAt.
+func (s *Store) CreateGrant(ctx context.Context, g *Grant) error {
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return err
+	}
+	g.ID = id
+	g.CreatedAt = time.Now().UTC()
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO bucket_grants (id, owner, bucket, prefix, grantee, permission, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		g.ID, g.Owner, g.Bucket, g.Prefix, g.Grantee, string(g.Permission), g.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrGrantExists
+	}
+	return err
+}
+
+// Grants возвращает права, выданные пользователем owner.
+func (s *Store) Grants(ctx context.Context, owner string) ([]Grant, error) {
+	return s.queryGrants(ctx, "owner", owner)
+}
+
+// ReceivedGrants возвращает права, выданные пользователю grantee.
+func (s *Store) ReceivedGrants(ctx context.Context, grantee string) ([]Grant, error) {
+	return s.queryGrants(ctx, "grantee", grantee)
+}
+
+// queryGrants выбирает права по колонке column — owner или grantee.
+func (s *Store) queryGrants(ctx context.Context, column, login string) ([]Grant, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, owner, bucket, prefix, grantee, permission, created_at
+		 FROM bucket_grants WHERE `+column+` = $1 ORDER BY created_at`, login)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []Grant{}
+	for rows.Next() {
+		var g Grant
+		if err := rows.Scan(&g.ID, &g.Owner, &g.Bucket, &g.Prefix, &g.Grantee, &g.Permission, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// RevokeGrant удаляет право id, выданное пользователем owner.
+func (s *Store) RevokeGrant(ctx context.Context, owner, id string) error {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM bucket_grants WHERE id = $1 AND owner = $2`, id, owner)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrGrantNotFound
+	}
+	return nil
+}
+
+// RevokeBucketGrants удаляет все права на бакет bucket пользователя owner.
+func (s *Store) RevokeBucketGrants(ctx context.Context, owner, bucket string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM bucket_grants WHERE owner = $1 AND bucket = $2`, owner, bucket)
+	return err
+}
+
+// Allowed сообщает, есть ли у grantee право perm на ключ key в бакете
+// bucket пользователя owner. Для списков key — запрошенный префикс.
+func (s *Store) Allowed(ctx context.Context, grantee, owner, bucket, key string, perm Permission) (bool, error) {
+	var ok bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (
+		     SELECT 1 FROM bucket_grants
+		     WHERE grantee = $1 AND owner = $2 AND bucket = $3 AND permission = $4
+		       AND left($5, length(prefix)) = prefix
+		 )`, grantee, owner, bucket, string(perm), key).Scan(&ok)
+	return ok, err
+}
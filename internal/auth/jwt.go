@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig — параметры проверки JWT, выданных OIDC-провайдером.
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	// LoginClaim — claim с логином пользователя хранилища.
+	LoginClaim string
+	// ScopeClaim — claim со списком scope: строка через пробел или массив.
+	ScopeClaim string
+	Leeway     time.Duration
+}
+
+// RoleFunc возвращает роль пользователя по логину.
+type RoleFunc func(ctx context.Context, login string) (Role, error)
+
+// JWTAuthenticator проверяет подписанные JWT по ключам из JWKS.
+type JWTAuthenticator struct {
+	keys   *KeySet
+	cfg    JWTConfig
+	roles  RoleFunc
+	parser *jwt.Parser
+}
+
+// NewJWTAuthenticator создаёт аутентификатор с ключами keys. Роль
+// пользователя определяет roles.
+func NewJWTAuthenticator(keys *KeySet, cfg JWTConfig, roles RoleFunc) *JWTAuthenticator {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return &JWTAuthenticator{keys: keys, cfg: cfg, roles: roles, parser: jwt.NewParser(opts...)}
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, raw string) (*Principal, error) {
+	// Токены из базы содержат одну точку, JWT — две.
+	if strings.Count(raw, ".") != 2 {
+		return nil, ErrInvalidToken
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	login, _ := claims[a.cfg.LoginClaim].(string)
+	if login == "" {
+		return nil, fmt.Errorf("%w: claim %q is missing", ErrInvalidToken, a.cfg.LoginClaim)
+	}
+
+	role, err := a.roles(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		Login:  login,
+		Role:   role,
+		Method: MethodJWT,
+		Scopes: scopes(claims[a.cfg.ScopeClaim]),
+	}, nil
+}
+
+// scopes разбирает claim со scope: строку через пробел или массив строк.
+func scopes(claim interface{}) []string {
+	list := []string{}
+	switch v := claim.(type) {
+	case string:
+		list = append(list, strings.Fields(v)...)
+	case []interface{}:
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
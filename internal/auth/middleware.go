@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Scope, которые обработчики объявляют при регистрации.
+const (
+	ScopeRead   = "storage:read"
+	ScopeWrite  = "storage:write"
+	ScopeDelete = "storage:delete"
+	ScopeAdmin  = "storage:admin"
+	ScopeTokens = "storage:tokens"
+	ScopeShare  = "storage:share"
+)
+
+type principalKey struct{}
+
+// WithPrincipal возвращает контекст с пользователем p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает пользователя, положенного в контекст Middleware.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Middleware проверяет заголовок Authorization: Bearer и кладёт
+// пользователя в контекст запроса. Без токена или с неверным токеном
+// запрос завершается кодом 401.
+func Middleware(a Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Извлечение токена из заголовка Authorization
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			unauthorized(w, "Authorization header is required")
+			return
+		}
+
+		// Проверка формата заголовка и извлечение токена
+		scheme, token, ok := strings.Cut(authHeader, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			unauthorized(w, "Invalid authorization header format")
+			return
+		}
+
+		principal, err := a.Authenticate(r.Context(), token)
+		if errors.Is(err, ErrInvalidToken) {
+			unauthorized(w, "Invalid or expired token")
+			return
+		}
+		if err != nil {
+			log.Println("authenticate:", err)
+			http.Error(w, "Ошибка проверки токена", http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// Require пропускает запрос, только если scope разрешён ролью
+// пользователя и его токеном. Используется внутри Middleware.
+func Require(scope string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := FromContext(r.Context())
+		if !ok {
+			unauthorized(w, "Authentication required")
+			return
+		}
+		if !principal.Can(scope) {
+			http.Error(w, "Недостаточно прав: требуется "+scope, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="storage"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
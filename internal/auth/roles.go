@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Role — роль пользователя хранилища.
+type Role string
+
+const (
+	// RoleAdmin управляет пользователями и имеет доступ ко всем хранилищам.
+	RoleAdmin Role = "admin"
+	// RoleOwner читает, загружает и удаляет файлы в своём хранилище.
+	RoleOwner Role = "owner"
+	// RoleReadOnly только читает файлы.
+	RoleReadOnly Role = "read-only"
+	// RoleUploader только загружает файлы.
+	RoleUploader Role = "uploader"
+)
+
+// roleScopes — scope, разрешённые каждой роли.
+var roleScopes = map[Role][]string{
+	RoleAdmin:    {ScopeRead, ScopeWrite, ScopeDelete, ScopeAdmin, ScopeTokens, ScopeShare},
+	RoleOwner:    {ScopeRead, ScopeWrite, ScopeDelete, ScopeTokens, ScopeShare},
+	RoleReadOnly: {ScopeRead, ScopeTokens},
+	RoleUploader: {ScopeWrite, ScopeTokens},
+}
+
+// ErrUnknownRole возвращается для роли вне списка.
+var ErrUnknownRole = errors.New("auth: unknown role")
+
+// ParseRole проверяет название роли.
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleScopes[role]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownRole, s)
+	}
+	return role, nil
+}
+
+// Allows сообщает, разрешает ли роль действие scope.
+func (r Role) Allows(scope string) bool {
+	for _, s := range roleScopes[r] {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Role возвращает роль пользователя login. Пользователи без записи в
+// Person считаются владельцами своего хранилища.
+func (s *Store) Role(ctx context.Context, login string) (Role, error) {
+	var role string
+	err := s.db.QueryRowContext(ctx, "SELECT role FROM Person WHERE login = $1", login).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RoleOwner, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return ParseRole(role)
+}
+
+// SetRole назначает роль пользователю login, создавая запись в Person
+// при необходимости.
+func (s *Store) SetRole(ctx context.Context, login string, role Role) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE Person SET role = $2 WHERE login = $1", login, string(role))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT INTO Person (login, role) VALUES ($1, $2)", login, string(role))
+	return err
+}
+
+// DeleteUser отзывает токены пользователя login и удаляет его запись,
+// выданные им и ему права, его бакеты со счётчиками заполнения, ссылки и
+// незавершённые загрузки, в том числе чужие ссылки и загрузки в его
+// бакетах. Всё удаляется в одной транзакции.
+func (s *Store) DeleteUser(ctx context.Context, login string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		"UPDATE api_tokens SET revoked_at = now() WHERE login = $1 AND revoked_at IS NULL",
+		"DELETE FROM bucket_grants WHERE owner = $1 OR grantee = $1",
+		"DELETE FROM shares WHERE owner = $1 OR bucket IN (SELECT name FROM buckets WHERE owner = $1)",
+		"DELETE FROM upload_sessions WHERE login = $1 OR bucket IN (SELECT name FROM buckets WHERE owner = $1)",
+		// Бакет по умолчанию (login-default-bucket) в buckets не записан.
+		"DELETE FROM bucket_usage WHERE bucket = $1 || '-default-bucket' OR bucket IN (SELECT name FROM buckets WHERE owner = $1)",
+		"DELETE FROM buckets WHERE owner = $1",
+		"DELETE FROM Person WHERE login = $1",
+	} {
+		if _, err := tx.ExecContext(ctx, query, login); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
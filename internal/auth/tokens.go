@@ -0,0 +1,234 @@
+// Package auth проверяет API-токены пользователей хранилища.
+//
+// Токен имеет вид "<id>.<secret>": по id находится запись, а secret
+// сравнивается с солёным хешем за постоянное время. Сами секреты в
+// базе не хранятся.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrInvalidToken возвращается для неизвестного, просроченного или
+	// отозванного токена.
+	ErrInvalidToken = errors.New("auth: invalid token")
+	// ErrTokenNotFound возвращается при отзыве несуществующего токена.
+	ErrTokenNotFound = errors.New("auth: token not found")
+	// ErrTokenExists возвращается, если у пользователя уже есть активный
+	// токен с таким именем.
+	ErrTokenExists = errors.New("auth: token with this name already exists")
+)
+
+// Token — сведения о выданном токене без секрета.
+type Token struct {
+	ID         string     `json:"id"`
+	Login      string     `json:"login"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// Scopes — разрешения токена; nil означает отсутствие ограничений.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Store хранит токены (api_tokens), роли (Person) и выданные права
+// доступа (bucket_grants).
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore создаёт Store поверх общего пула соединений.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Issue выпускает токен name для login. Если ttl больше нуля, токен
+// перестаёт действовать через ttl. scopes ограничивает разрешения
+// токена, nil — без ограничений. Секрет возвращается только здесь.
+func (s *Store) Issue(ctx context.Context, login, name string, ttl time.Duration, scopes []string) (string, *Token, error) {
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", nil, err
+	}
+
+	tok := &Token{ID: id, Login: login, Name: name, CreatedAt: time.Now().UTC(), Scopes: scopes}
+	if ttl > 0 {
+		expires := tok.CreatedAt.Add(ttl)
+		tok.ExpiresAt = &expires
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO api_tokens (id, login, name, salt, token_hash, created_at, expires_at, scopes)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		tok.ID, tok.Login, tok.Name, salt, hashSecret(salt, secret), tok.CreatedAt, tok.ExpiresAt, pq.StringArray(scopes))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return "", nil, ErrTokenExists
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return id + "." + secret, tok, nil
+}
+
+// Authenticate проверяет токен из базы. Разрешения principal
+// ограничены scope, записанными в токен при выпуске.
+func (s *Store) Authenticate(ctx context.Context, raw string) (*Principal, error) {
+	tok, err := s.Verify(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	role, err := s.Role(ctx, tok.Login)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{Login: tok.Login, Role: role, Method: MethodToken, TokenID: tok.ID, Scopes: tok.Scopes}, nil
+}
+
+// Verify проверяет токен и отмечает время его использования. Токены,
+// перенесённые из колонки Person.token, не имеют вида "<id>.<secret>":
+// их id вычисляется из самого токена (см. legacyTokenID).
+func (s *Store) Verify(ctx context.Context, raw string) (*Token, error) {
+	if raw == "" {
+		return nil, ErrInvalidToken
+	}
+	id, secret, ok := strings.Cut(raw, ".")
+	if ok && id != "" && secret != "" {
+		tok, err := s.verify(ctx, id, secret)
+		if !errors.Is(err, ErrInvalidToken) {
+			return tok, err
+		}
+	}
+	return s.verify(ctx, legacyTokenID(raw), raw)
+}
+
+// legacyTokenID — id токена, перенесённого из Person.token: первые 8
+// байт SHA-256 от токена в шестнадцатеричной записи.
+func legacyTokenID(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *Store) verify(ctx context.Context, id, secret string) (*Token, error) {
+	var (
+		tok       Token
+		salt      []byte
+		tokenHash []byte
+		scopes    pq.StringArray
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, login, name, created_at, expires_at, last_used_at, revoked_at, scopes, salt, token_hash
+		 FROM api_tokens WHERE id = $1`, id).
+		Scan(&tok.ID, &tok.Login, &tok.Name, &tok.CreatedAt, &tok.ExpiresAt, &tok.LastUsedAt, &tok.RevokedAt, &scopes, &salt, &tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(hashSecret(salt, secret), tokenHash) != 1 {
+		return nil, ErrInvalidToken
+	}
+	tok.Scopes = scopes
+	if tok.RevokedAt != nil || (tok.ExpiresAt != nil && time.Now().After(*tok.ExpiresAt)) {
+		return nil, ErrInvalidToken
+	}
+
+	// Время использования обновляется не чаще раза в минуту, чтобы не
+	// писать в базу на каждый запрос.
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE api_tokens SET last_used_at = now()
+		 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &tok, nil
+}
+
+// List возвращает токены пользователя, включая отозванные.
+func (s *Store) List(ctx context.Context, login string) ([]Token, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, login, name, created_at, expires_at, last_used_at, revoked_at, scopes
+		 FROM api_tokens WHERE login = $1 ORDER BY created_at`, login)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []Token{}
+	for rows.Next() {
+		var (
+			tok    Token
+			scopes pq.StringArray
+		)
+		if err := rows.Scan(&tok.ID, &tok.Login, &tok.Name, &tok.CreatedAt, &tok.ExpiresAt, &tok.LastUsedAt, &tok.RevokedAt, &scopes); err != nil {
+			return nil, err
+		}
+		tok.Scopes = scopes
+		tokens = append(tokens, tok)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke отзывает токен id пользователя login.
+func (s *Store) Revoke(ctx context.Context, login, id string) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE api_tokens SET revoked_at = now() WHERE id = $1 AND login = $2 AND revoked_at IS NULL`, id, login)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// RevokeAll отзывает все активные токены пользователя login и
+// возвращает их число.
+func (s *Store) RevokeAll(ctx context.Context, login string) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE api_tokens SET revoked_at = now() WHERE login = $1 AND revoked_at IS NULL`, login)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func hashSecret(salt []byte, secret string) []byte {
+	h := hmac.New(sha256.New, salt)
+	h.Write([]byte(secret))
+	return h.Sum(nil)
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return encode(buf), nil
+}
@@ -0,0 +1,111 @@
+// Package cache хранит значения со сроком жизни. Одновременные промахи
+// по одному ключу загружают значение один раз: остальные вызовы ждут
+// результата первой загрузки.
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache — кэш значений типа V по строковому ключу. Ошибки загрузки не
+// кэшируются.
+type Cache[V any] struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]entry[V]
+	calls   map[string]*call[V]
+	// gen увеличивается при каждой инвалидации: значение, загрузка
+	// которого началась раньше, в кэш уже не попадает.
+	gen uint64
+}
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// New создаёт кэш со сроком жизни значений ttl. При ttl <= 0 значения
+// не сохраняются, но одновременные загрузки всё равно объединяются.
+func New[V any](ttl time.Duration) *Cache[V] {
+	return &Cache[V]{
+		ttl:     ttl,
+		entries: make(map[string]entry[V]),
+		calls:   make(map[string]*call[V]),
+	}
+}
+
+// Get возвращает значение key из кэша или загружает его через load.
+// Загрузка не прерывается, если ctx вызвавшего её запроса отменён:
+// результата могут ждать другие запросы.
+func (c *Cache[V]) Get(ctx context.Context, key string, load func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if time.Now().Before(e.expires) {
+			c.mu.Unlock()
+			return e.value, nil
+		}
+		delete(c.entries, key)
+	}
+	if cl, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		return cl.wait(ctx)
+	}
+	cl := &call[V]{done: make(chan struct{})}
+	c.calls[key] = cl
+	gen := c.gen
+	c.mu.Unlock()
+
+	go func() {
+		cl.value, cl.err = load(context.WithoutCancel(ctx))
+
+		c.mu.Lock()
+		if c.calls[key] == cl {
+			delete(c.calls, key)
+		}
+		if cl.err == nil && c.ttl > 0 && c.gen == gen {
+			c.entries[key] = entry[V]{value: cl.value, expires: time.Now().Add(c.ttl)}
+		}
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+	return cl.wait(ctx)
+}
+
+func (cl *call[V]) wait(ctx context.Context) (V, error) {
+	select {
+	case <-cl.done:
+		return cl.value, cl.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// Invalidate удаляет значение key. Идущая загрузка key завершится для
+// тех, кто её уже ждёт, но её результат не будет сохранён, а следующий
+// Get начнёт новую загрузку.
+func (c *Cache[V]) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	delete(c.calls, key)
+	c.gen++
+}
+
+// Purge удаляет все значения.
+func (c *Cache[V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]entry[V])
+	c.calls = make(map[string]*call[V])
+	c.gen++
+}
@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetCachesValue(t *testing.T) {
+	c := New[int](time.Minute)
+	var loads atomic.Int32
+	load := func(context.Context) (int, error) {
+		return int(loads.Add(1)), nil
+	}
+
+	for i := 0; i < 3; i++ {
+		v, err := c.Get(context.Background(), "k", load)
+		if err != nil || v != 1 {
+			t.Fatalf("Get = %d, %v, want 1", v, err)
+		}
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loads = %d, want 1", n)
+	}
+
+	if v, _ := c.Get(context.Background(), "other", load); v != 2 {
+		t.Errorf("Get(other) = %d, want 2", v)
+	}
+}
+
+func TestGetExpires(t *testing.T) {
+	c := New[int](20 * time.Millisecond)
+	var loads atomic.Int32
+	load := func(context.Context) (int, error) {
+		return int(loads.Add(1)), nil
+	}
+
+	c.Get(context.Background(), "k", load)
+	time.Sleep(40 * time.Millisecond)
+	if v, _ := c.Get(context.Background(), "k", load); v != 2 {
+		t.Errorf("Get after ttl = %d, want 2", v)
+	}
+}
+
+func TestGetZeroTTL(t *testing.T) {
+	c := New[int](0)
+	var loads atomic.Int32
+	load := func(context.Context) (int, error) {
+		return int(loads.Add(1)), nil
+	}
+
+	c.Get(context.Background(), "k", load)
+	c.Get(context.Background(), "k", load)
+	if n := loads.Load(); n != 2 {
+		t.Errorf("loads = %d, want 2", n)
+	}
+}
+
+func TestGetDoesNotCacheErrors(t *testing.T) {
+	c := New[int](time.Minute)
+	fail := errors.New("boom")
+
+	if _, err := c.Get(context.Background(), "k", func(context.Context) (int, error) { return 0, fail }); !errors.Is(err, fail) {
+		t.Fatalf("Get error = %v, want %v", err, fail)
+	}
+	v, err := c.Get(context.Background(), "k", func(context.Context) (int, error) { return 7, nil })
+	if err != nil || v != 7 {
+		t.Fatalf("Get after error = %d, %v, want 7", v, err)
+	}
+}
+
+func TestGetSingleflight(t *testing.T) {
+	c := New[int](time.Minute)
+	release := make(chan struct{})
+	var loads atomic.Int32
+	load := func(context.Context) (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = c.Get(context.Background(), "k", load)
+		}(i)
+	}
+	// Даём всем вызовам дождаться общей загрузки.
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.calls["k"] != nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loads = %d, want 1", n)
+	}
+	for i, v := range results {
+		if v != 42 {
+			t.Errorf("results[%d] = %d, want 42", i, v)
+		}
+	}
+}
+
+func TestGetCallerCanceled(t *testing.T) {
+	c := New[int](time.Minute)
+	release := make(chan struct{})
+	loaded := make(chan error, 1)
+	load := func(ctx context.Context) (int, error) {
+		<-release
+		loaded <- ctx.Err()
+		return 5, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Get(ctx, "k", load); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Get error = %v, want context.Canceled", err)
+	}
+
+	// Загрузка продолжается без отменённого контекста, и её результат
+	// сохраняется для следующих вызовов.
+	close(release)
+	if err := <-loaded; err != nil {
+		t.Errorf("load ctx error = %v, want nil", err)
+	}
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		_, ok := c.entries["k"]
+		return ok
+	})
+	v, err := c.Get(context.Background(), "k", func(context.Context) (int, error) { return 0, errors.New("unexpected load") })
+	if err != nil || v != 5 {
+		t.Fatalf("Get = %d, %v, want 5", v, err)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	c := New[int](time.Minute)
+	var loads atomic.Int32
+	load := func(context.Context) (int, error) {
+		return int(loads.Add(1)), nil
+	}
+
+	c.Get(context.Background(), "k", load)
+	c.Invalidate("k")
+	if v, _ := c.Get(context.Background(), "k", load); v != 2 {
+		t.Errorf("Get after Invalidate = %d, want 2", v)
+	}
+
+	c.Get(context.Background(), "other", load)
+	c.Purge()
+	if v, _ := c.Get(context.Background(), "other", load); v != 4 {
+		t.Errorf("Get after Purge = %d, want 4", v)
+	}
+}
+
+func TestInvalidateDuringLoad(t *testing.T) {
+	c := New[int](time.Minute)
+	release := make(chan struct{})
+	done := make(chan int)
+	go func() {
+		v, _ := c.Get(context.Background(), "k", func(context.Context) (int, error) {
+			<-release
+			return 1, nil
+		})
+		done <- v
+	}()
+	waitFor(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.calls["k"] != nil
+	})
+
+	// Значение, загрузка которого началась до инвалидации, достаётся
+	// уже ждущему вызову, но не попадает в кэш.
+	c.Invalidate("k")
+	close(release)
+	if v := <-done; v != 1 {
+		t.Errorf("waiting Get = %d, want 1", v)
+	}
+	v, err := c.Get(context.Background(), "k", func(context.Context) (int, error) { return 2, nil })
+	if err != nil || v != 2 {
+		t.Fatalf("Get after Invalidate = %d, %v, want 2", v, err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
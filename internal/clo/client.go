@@ -0,0 +1,190 @@
+// Package clo реализует клиент административного API облака CLO
+// (https://api.clo.ru): проекты, пользователи S3, квоты и ключи доступа.
+package clo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL — адрес API CLO по умолчанию.
+const DefaultBaseURL = "https://api.clo.ru"
+
+// Client выполняет запросы к API CLO от имени владельца API-токена.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option настраивает Client.
+type Option func(*Client)
+
+// WithBaseURL переопределяет адрес API.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient задаёт http.Client, через который выполняются запросы.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewClient создаёт клиент, авторизующийся токеном token.
+func NewClient(token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    DefaultBaseURL,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ListProjects возвращает проекты, доступные владельцу токена.
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var resp listResponse[Project]
+	if err := c.do(ctx, http.MethodGet, "/v2/projects", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// DefaultProjectID возвращает идентификатор первого проекта.
+func (c *Client) DefaultProjectID(ctx context.Context) (string, error) {
+	projects, err := c.ListProjects(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(projects) == 0 {
+		return "", ErrNoProject
+	}
+	return projects[0].ID, nil
+}
+
+// ListUsers возвращает пользователей S3 проекта.
+func (c *Client) ListUsers(ctx context.Context, projectID string) ([]S3User, error) {
+	var resp listResponse[S3User]
+	if err := c.do(ctx, http.MethodGet, "/v2/projects/"+projectID+"/s3/users", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// FindUser ищет пользователя S3 по имени. Если пользователя нет,
+// возвращается ошибка ErrUserNotFound.
+func (c *Client) FindUser(ctx context.Context, projectID, name string) (*S3User, error) {
+	users, err := c.ListUsers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		if users[i].Name == name {
+			return &users[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUserNotFound, name)
+}
+
+// CreateUser создаёт пользователя S3 в проекте.
+func (c *Client) CreateUser(ctx context.Context, projectID string, req CreateUserRequest) (*S3User, error) {
+	var resp itemResponse[S3User]
+	if err := c.do(ctx, http.MethodPost, "/v2/projects/"+projectID+"/s3/users", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Result, nil
+}
+
+// DeleteUser удаляет пользователя S3.
+func (c *Client) DeleteUser(ctx context.Context, userID string) error {
+	return c.do(ctx, http.MethodDelete, "/v2/s3/users/"+userID, nil, nil)
+}
+
+// UpdateQuota меняет квоту пользователя S3 или его бакетов.
+func (c *Client) UpdateQuota(ctx context.Context, userID string, req UpdateQuotaRequest) error {
+	return c.do(ctx, http.MethodPut, "/v2/s3/users/"+userID+"/quotas", req, nil)
+}
+
+// ListCredentials возвращает ключи доступа пользователя S3.
+func (c *Client) ListCredentials(ctx context.Context, userID string) ([]Credentials, error) {
+	var resp listResponse[Credentials]
+	if err := c.do(ctx, http.MethodGet, "/v2/s3/users/"+userID+"/credentials", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// CreateCredentials выпускает пользователю S3 новую пару ключей.
+// Прежние ключи продолжают действовать, пока их не удалят.
+func (c *Client) CreateCredentials(ctx context.Context, userID string) (*Credentials, error) {
+	var resp itemResponse[Credentials]
+	if err := c.do(ctx, http.MethodPost, "/v2/s3/users/"+userID+"/credentials", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Result, nil
+}
+
+// DeleteCredentials удаляет пару ключей credentialsID пользователя S3.
+func (c *Client) DeleteCredentials(ctx context.Context, userID, credentialsID string) error {
+	return c.do(ctx, http.MethodDelete, "/v2/s3/users/"+userID+"/credentials/"+credentialsID, nil, nil)
+}
+
+// do отправляет запрос с телом in (если не nil) и декодирует ответ в out
+// (если не nil). Ответы с кодом вне диапазона 2xx возвращаются как *APIError.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("clo: encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("clo: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("clo: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("clo: read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Method:     method,
+			Path:       path,
+			Body:       string(data),
+		}
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("clo: decode response: %w", err)
+	}
+	return nil
+}
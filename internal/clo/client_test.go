@@ -0,0 +1,179 @@
+package clo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient("secret", WithBaseURL(srv.URL+"/"), WithHTTPClient(srv.Client()))
+}
+
+func TestClientDecodesResponse(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v2/projects/p1/s3/users" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		w.Write([]byte(`{"count": 2, "result": [
+			{"id": "u1", "name": "alice", "quotas": [{"type": "user", "max_objects": 10, "max_size": 100}]},
+			{"id": "u2", "name": "bob", "quotas": [{"type": "bucket", "max_objects": null, "max_size": 5}]}
+		]}`))
+	})
+
+	user, err := c.FindUser(context.Background(), "p1", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "u2" || len(user.Quotas) != 1 {
+		t.Fatalf("user = %+v", user)
+	}
+	if q := user.Quotas[0]; q.Type != QuotaTypeBucket || q.MaxObjects != nil || q.MaxSize != 5 {
+		t.Errorf("quota = %+v", q)
+	}
+
+	_, err = c.FindUser(context.Background(), "p1", "carol")
+	if !errors.Is(err, ErrUserNotFound) || !IsNotFound(err) {
+		t.Errorf("FindUser(carol) error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestClientCreateUser(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/projects/p1/s3/users" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		var req CreateUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		want := CreateUserRequest{Name: "alice", CanonicalName: "alice", MaxBuckets: 3, UserQuota: QuotaLimits{MaxObjects: 10, MaxSize: 100}}
+		if req != want {
+			t.Errorf("body = %+v, want %+v", req, want)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"result": {"id": "u1", "name": "alice", "max_buckets": 3}}`))
+	})
+
+	user, err := c.CreateUser(context.Background(), "p1", CreateUserRequest{
+		Name:          "alice",
+		CanonicalName: "alice",
+		MaxBuckets:    3,
+		UserQuota:     QuotaLimits{MaxObjects: 10, MaxSize: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != "u1" || user.MaxBuckets != 3 {
+		t.Errorf("user = %+v", user)
+	}
+}
+
+func TestClientUpdateQuota(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/v2/s3/users/u1/quotas" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		var req UpdateQuotaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		if req != (UpdateQuotaRequest{Type: QuotaTypeUser, MaxObjects: 7, MaxSize: 42}) {
+			t.Errorf("body = %+v", req)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := c.UpdateQuota(context.Background(), "u1", UpdateQuotaRequest{Type: QuotaTypeUser, MaxObjects: 7, MaxSize: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientAPIError(t *testing.T) {
+	tests := []struct {
+		status   int
+		notFound bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusBadRequest, false},
+		{http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"error": "boom"}`, tt.status)
+		})
+
+		err := c.DeleteUser(context.Background(), "u1")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("status %d: error = %v, want *APIError", tt.status, err)
+		}
+		if apiErr.StatusCode != tt.status || apiErr.Method != http.MethodDelete || apiErr.Path != "/v2/s3/users/u1" {
+			t.Errorf("status %d: APIError = %+v", tt.status, apiErr)
+		}
+		if apiErr.Body != "{\"error\": \"boom\"}\n" {
+			t.Errorf("status %d: Body = %q", tt.status, apiErr.Body)
+		}
+		if IsNotFound(err) != tt.notFound {
+			t.Errorf("status %d: IsNotFound = %v", tt.status, !tt.notFound)
+		}
+	}
+}
+
+func TestClientDecodeError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": [`))
+	})
+
+	_, err := c.ListProjects(context.Background())
+	var apiErr *APIError
+	if err == nil || errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want decode error", err)
+	}
+}
+
+func TestClientNoProject(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"count": 0, "result": []}`))
+	})
+
+	if _, err := c.DefaultProjectID(context.Background()); !errors.Is(err, ErrNoProject) {
+		t.Fatalf("error = %v, want ErrNoProject", err)
+	}
+}
+
+func TestClientContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.ListProjects(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want context.DeadlineExceeded", err)
+	}
+}
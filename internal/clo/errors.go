@@ -0,0 +1,37 @@
+package clo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNoProject возвращается, если у владельца токена нет ни одного проекта.
+	ErrNoProject = errors.New("clo: no project found")
+	// ErrUserNotFound возвращается, если пользователь S3 с таким именем не найден.
+	ErrUserNotFound = errors.New("clo: s3 user not found")
+	// ErrNoCredentials возвращается, если у пользователя S3 нет ключей доступа.
+	ErrNoCredentials = errors.New("clo: s3 user has no credentials")
+)
+
+// APIError описывает ответ API с кодом вне диапазона 2xx.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("clo: %s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// IsNotFound сообщает, что ошибка означает отсутствие запрошенного объекта.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+	return errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrNoProject)
+}
@@ -0,0 +1,79 @@
+package clo
+
+// Project — проект в облаке CLO.
+type Project struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Status         string  `json:"status"`
+	HasAbuse       bool    `json:"has_abuse"`
+	CreatedIn      string  `json:"created_in"`
+	StoppingReason *string `json:"stopping_reason"`
+}
+
+// Quota — квота пользователя S3 или его бакетов.
+type Quota struct {
+	Type       string `json:"type"`
+	MaxObjects *int   `json:"max_objects"`
+	MaxSize    int    `json:"max_size"`
+}
+
+// Типы квот пользователя S3.
+const (
+	QuotaTypeUser   = "user"
+	QuotaTypeBucket = "bucket"
+)
+
+// S3User — пользователь объектного хранилища.
+type S3User struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	CanonicalName string  `json:"canonical_name"`
+	Tenant        *string `json:"tenant"`
+	MaxBuckets    int     `json:"max_buckets"`
+	Quotas        []Quota `json:"quotas"`
+	Status        string  `json:"status"`
+}
+
+// QuotaLimits — ограничения, передаваемые при создании пользователя.
+type QuotaLimits struct {
+	MaxObjects int `json:"max_objects"`
+	MaxSize    int `json:"max_size"`
+}
+
+// CreateUserRequest — параметры создания пользователя S3.
+type CreateUserRequest struct {
+	Name          string      `json:"name"`
+	CanonicalName string      `json:"canonical_name"`
+	DefaultBucket bool        `json:"default_bucket"`
+	MaxBuckets    int         `json:"max_buckets"`
+	BucketQuota   QuotaLimits `json:"bucket_quota"`
+	UserQuota     QuotaLimits `json:"user_quota"`
+}
+
+// UpdateQuotaRequest — новые ограничения квоты типа Type.
+type UpdateQuotaRequest struct {
+	Type       string `json:"type"`
+	MaxObjects int    `json:"max_objects"`
+	MaxSize    int    `json:"max_size"`
+}
+
+// Credentials — пара ключей доступа пользователя S3.
+type Credentials struct {
+	ID        string `json:"id"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	Status    string `json:"status"`
+	CreatedIn string `json:"created_in"`
+}
+
+// CredentialsActive — статус действующей пары ключей.
+const CredentialsActive = "active"
+
+type listResponse[T any] struct {
+	Result []T `json:"result"`
+	Count  int `json:"count"`
+}
+
+type itemResponse[T any] struct {
+	Result T `json:"result"`
+}
@@ -0,0 +1,204 @@
+// Package config загружает настройки сервиса из configs/config.yml.
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config — настройки сервиса.
+type Config struct {
+	DB      DB      `mapstructure:"db"`
+	Storage Storage `mapstructure:"storage"`
+	Auth    Auth    `mapstructure:"auth"`
+	CLO     CLO     `mapstructure:"clo"`
+
+	// Plans — тарифы, с которыми создаются пользователи; DefaultPlan —
+	// тариф, если он не указан при создании.
+	Plans       map[string]Plan `mapstructure:"plans"`
+	DefaultPlan string          `mapstructure:"default_plan"`
+}
+
+// Plan — квоты и ограничения пользователя хранилища CLO.
+type Plan struct {
+	MaxBuckets    int   `mapstructure:"max_buckets"`
+	DefaultBucket bool  `mapstructure:"default_bucket"`
+	BucketQuota   Quota `mapstructure:"bucket_quota"`
+	UserQuota     Quota `mapstructure:"user_quota"`
+}
+
+// Quota — ограничение числа объектов и их общего размера в мегабайтах.
+type Quota struct {
+	MaxObjects int `mapstructure:"max_objects"`
+	MaxSize    int `mapstructure:"max_size"`
+}
+
+// CLO — параметры работы с API CLO.
+type CLO struct {
+	// CacheTTL — сколько хранятся проект, идентификаторы пользователей
+	// и их ключи, полученные из API.
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+// Auth — параметры API-токенов.
+type Auth struct {
+	// TokenTTL — срок действия токена, если он не указан при выпуске.
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
+	JWT      JWT           `mapstructure:"jwt"`
+}
+
+// JWT — проверка access-токенов OIDC-провайдера. JWKS берётся из
+// файла JWKSFile или по адресу JWKSURL.
+type JWT struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	JWKSURL         string        `mapstructure:"jwks_url"`
+	JWKSFile        string        `mapstructure:"jwks_file"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	Issuer          string        `mapstructure:"issuer"`
+	Audience        string        `mapstructure:"audience"`
+	LoginClaim      string        `mapstructure:"login_claim"`
+	ScopeClaim      string        `mapstructure:"scope_claim"`
+	Leeway          time.Duration `mapstructure:"leeway"`
+}
+
+// DB — параметры подключения к Postgres.
+type DB struct {
+	Username string `mapstructure:"username"`
+	Host     string `mapstructure:"host"`
+	Port     string `mapstructure:"port"`
+	Password string `mapstructure:"password"`
+	DBName   string `mapstructure:"dbname"`
+	SSLMode  string `mapstructure:"sslmode"`
+}
+
+// Storage — параметры объектного хранилища.
+//
+// Backend выбирает реализацию: "clo" — хранилище CLO с ключами,
+// получаемыми через API для каждого пользователя; "s3" — любое
+// S3-совместимое хранилище со статическими ключами; "local" — каталог
+// LocalDir на диске.
+type Storage struct {
+	Backend        string  `mapstructure:"backend"`
+	Endpoint       string  `mapstructure:"endpoint"`
+	Region         string  `mapstructure:"region"`
+	ForcePathStyle bool    `mapstructure:"force_path_style"`
+	AccessKey      string  `mapstructure:"access_key"`
+	SecretKey      string  `mapstructure:"secret_key"`
+	LocalDir       string  `mapstructure:"local_dir"`
+	Upload         Upload  `mapstructure:"upload"`
+	Presign        Presign `mapstructure:"presign"`
+	Shares         Shares  `mapstructure:"shares"`
+	Client         Client  `mapstructure:"client"`
+	ACL            ACL     `mapstructure:"acl"`
+	Usage          Usage   `mapstructure:"usage"`
+}
+
+// Usage — учёт заполнения бакетов для проверки квот.
+type Usage struct {
+	// RecountInterval — как часто счётчик бакета сверяется со списком
+	// объектов.
+	RecountInterval time.Duration `mapstructure:"recount_interval"`
+}
+
+// ACL — видимость загружаемых объектов: ACL по умолчанию и значения,
+// которые разрешено указывать при загрузке и менять через /set-file-acl.
+type ACL struct {
+	Default string   `mapstructure:"default"`
+	Allowed []string `mapstructure:"allowed"`
+}
+
+// Client — общий HTTP-транспорт клиентов S3 и пул клиентов
+// пользователей CLO.
+type Client struct {
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+
+	// IdleTimeout — через сколько неиспользуемый клиент пользователя
+	// удаляется из пула.
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+
+	// MaxAttempts и MaxBackoff — число попыток запроса к хранилищу и
+	// наибольшая пауза между ними.
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	MaxBackoff  time.Duration `mapstructure:"max_backoff"`
+
+	// LogRequests включает журнал запросов к хранилищу.
+	LogRequests bool `mapstructure:"log_requests"`
+}
+
+// Shares — параметры публичных ссылок /s/{id}.
+type Shares struct {
+	DefaultTTL time.Duration `mapstructure:"default_ttl"`
+	MaxTTL     time.Duration `mapstructure:"max_ttl"`
+
+	// PublicURL — внешний адрес сервиса, от которого строятся ссылки
+	// /s/{id}.
+	PublicURL string `mapstructure:"public_url"`
+}
+
+// Presign — параметры подписанных ссылок для прямого доступа к бакету.
+type Presign struct {
+	DefaultTTL    time.Duration `mapstructure:"default_ttl"`
+	MaxTTL        time.Duration `mapstructure:"max_ttl"`
+	MaxPostSizeMB int64         `mapstructure:"max_post_size_mb"`
+}
+
+// Upload — параметры потоковой загрузки файлов через multipart upload.
+type Upload struct {
+	PartSizeMB  int64 `mapstructure:"part_size_mb"`
+	Concurrency int   `mapstructure:"concurrency"`
+
+	// ResumableTTL — сколько живёт незавершённая возобновляемая загрузка.
+	ResumableTTL time.Duration `mapstructure:"resumable_ttl"`
+}
+
+// Load читает config.yml из каталога path.
+func Load(path string) (*Config, error) {
+	v := viper.New()
+	v.AddConfigPath(path)
+	v.SetConfigName("config")
+
+	v.SetDefault("storage.backend", "clo")
+	v.SetDefault("storage.endpoint", "https://storage.clo.ru")
+	v.SetDefault("storage.region", "us-west-2")
+	v.SetDefault("storage.force_path_style", true)
+	v.SetDefault("storage.local_dir", "data")
+	v.SetDefault("storage.upload.part_size_mb", 16)
+	v.SetDefault("storage.upload.concurrency", 4)
+	v.SetDefault("storage.upload.resumable_ttl", "24h")
+	v.SetDefault("storage.presign.default_ttl", "15m")
+	v.SetDefault("storage.presign.max_ttl", "168h")
+	v.SetDefault("storage.presign.max_post_size_mb", 5120)
+	v.SetDefault("storage.client.max_idle_conns", 256)
+	v.SetDefault("storage.client.max_idle_conns_per_host", 64)
+	v.SetDefault("storage.client.idle_conn_timeout", "90s")
+	v.SetDefault("storage.client.idle_timeout", "10m")
+	v.SetDefault("storage.client.max_attempts", 3)
+	v.SetDefault("storage.acl.default", "private")
+	v.SetDefault("storage.acl.allowed", []string{"private", "public-read"})
+	v.SetDefault("storage.client.max_backoff", "20s")
+	v.SetDefault("storage.shares.default_ttl", "24h")
+	v.SetDefault("storage.shares.max_ttl", "720h")
+	v.SetDefault("storage.shares.public_url", "https://127.0.0.1:8443")
+	v.SetDefault("storage.usage.recount_interval", "1h")
+	v.SetDefault("clo.cache_ttl", "5m")
+	v.SetDefault("default_plan", "basic")
+	v.SetDefault("auth.token_ttl", "2160h")
+	v.SetDefault("auth.jwt.refresh_interval", "1h")
+	v.SetDefault("auth.jwt.login_claim", "preferred_username")
+	v.SetDefault("auth.jwt.scope_claim", "scope")
+	v.SetDefault("auth.jwt.leeway", "30s")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+	return &cfg, nil
+}
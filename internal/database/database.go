@@ -0,0 +1,55 @@
+// Package database открывает общий пул соединений с Postgres и
+// применяет схему сервиса.
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"S3Storage/internal/config"
+
+	_ "github.com/lib/pq"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Open открывает пул соединений и проверяет доступность базы.
+func Open(cfg config.DB) (*sql.DB, error) {
+	dsn := fmt.Sprintf("host=%s user=%s port=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Username, cfg.Port, cfg.Password, cfg.DBName, cfg.SSLMode)
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+	return db, nil
+}
+
+// Migrate применяет файлы migrations/*.sql в порядке имён. Все
+// миграции идемпотентны и выполняются при каждом запуске.
+func Migrate(db *sql.DB) error {
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		query, err := migrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(string(query)); err != nil {
+			return fmt.Errorf("apply %s: %w", name, err)
+		}
+	}
+	return nil
+}
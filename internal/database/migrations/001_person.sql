@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS Person (
+    login TEXT PRIMARY KEY,
+    token TEXT
+);
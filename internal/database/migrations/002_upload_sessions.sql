@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id            TEXT PRIMARY KEY,
+    login         TEXT NOT NULL,
+    bucket        TEXT NOT NULL,
+    object_key    TEXT NOT NULL,
+    s3_upload_id  TEXT NOT NULL,
+    total_size    BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS upload_sessions_expires_at_idx ON upload_sessions (expires_at);
+
+CREATE TABLE IF NOT EXISTS upload_parts (
+    session_id  TEXT NOT NULL REFERENCES upload_sessions (id) ON DELETE CASCADE,
+    part_number INTEGER NOT NULL,
+    etag        TEXT NOT NULL,
+    size        BIGINT NOT NULL,
+    PRIMARY KEY (session_id, part_number)
+);
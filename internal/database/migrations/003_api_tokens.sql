@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id           TEXT PRIMARY KEY,
+    login        TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    salt         BYTEA NOT NULL,
+    token_hash   BYTEA NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_login_idx ON api_tokens (login);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_tokens_login_name_idx
+    ON api_tokens (login, name) WHERE revoked_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE Person ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'owner';
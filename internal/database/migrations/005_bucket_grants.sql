@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS bucket_grants (
+    id         TEXT PRIMARY KEY,
+    owner      TEXT NOT NULL,
+    bucket     TEXT NOT NULL,
+    prefix     TEXT NOT NULL DEFAULT '',
+    grantee    TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS bucket_grants_unique_idx
+    ON bucket_grants (owner, bucket, prefix, grantee, permission);
+
+CREATE INDEX IF NOT EXISTS bucket_grants_grantee_idx ON bucket_grants (grantee);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS shares (
+    id            TEXT PRIMARY KEY,
+    owner         TEXT NOT NULL,
+    bucket        TEXT NOT NULL,
+    object_key    TEXT NOT NULL,
+    password_salt BYTEA,
+    password_hash BYTEA,
+    max_downloads INTEGER,
+    downloads     INTEGER NOT NULL DEFAULT 0,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at    TIMESTAMPTZ NOT NULL,
+    revoked_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS shares_owner_idx ON shares (owner);
+
+CREATE TABLE IF NOT EXISTS share_accesses (
+    id          BIGSERIAL PRIMARY KEY,
+    share_id    TEXT NOT NULL REFERENCES shares (id) ON DELETE CASCADE,
+    accessed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    remote_addr TEXT NOT NULL,
+    user_agent  TEXT NOT NULL,
+    status      INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS share_accesses_share_idx ON share_accesses (share_id, accessed_at);
@@ -0,0 +1 @@
+ALTER TABLE Person ADD COLUMN IF NOT EXISTS plan TEXT;
@@ -0,0 +1,4 @@
+-- Имена бакетов по умолчанию принадлежат своим пользователям: записи,
+-- занявшие чужой бакет по умолчанию, удаляются.
+DELETE FROM buckets
+WHERE name LIKE '%-default-bucket' AND name <> owner || '-default-bucket';
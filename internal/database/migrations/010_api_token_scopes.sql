@@ -0,0 +1,2 @@
+-- Ограничения токена по scope. NULL — без ограничений, кроме роли.
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS scopes TEXT[];
@@ -0,0 +1,9 @@
+-- Счётчик заполнения бакетов для проверки квот: обновляется при записи
+-- и удалении через сервис и периодически пересчитывается по списку
+-- объектов.
+CREATE TABLE IF NOT EXISTS bucket_usage (
+    bucket      TEXT PRIMARY KEY,
+    objects     BIGINT NOT NULL DEFAULT 0,
+    bytes       BIGINT NOT NULL DEFAULT 0,
+    measured_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+-- Отметка о том, что часть возобновляемой загрузки передаётся в
+-- хранилище: пока она действует, другие PATCH этой загрузки отклоняются.
+ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS part_lease_until TIMESTAMPTZ;
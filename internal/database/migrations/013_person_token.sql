@@ -0,0 +1,20 @@
+-- Токены из колонки Person.token переносятся в api_tokens в виде
+-- солёного хеша (имя "legacy"), после чего колонка удаляется. id такого
+-- токена — начало SHA-256 от него самого: по нему находится запись,
+-- когда клиент передаёт токен старого вида без "<id>.".
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'person' AND column_name = 'token') THEN
+        INSERT INTO api_tokens (id, login, name, salt, token_hash)
+        SELECT left(encode(sha256(convert_to(token, 'UTF8')), 'hex'), 16), login, 'legacy', salt,
+               hmac(convert_to(token, 'UTF8'), salt, 'sha256')
+        FROM (SELECT login, token, gen_random_bytes(16) AS salt
+              FROM Person WHERE token IS NOT NULL AND token <> '') AS legacy
+        ON CONFLICT DO NOTHING;
+
+        ALTER TABLE Person DROP COLUMN token;
+    END IF;
+END $$;
@@ -0,0 +1,2 @@
+-- Пользователь без тарифа (с явно заданными квотами) хранится с NULL.
+UPDATE Person SET plan = NULL WHERE plan = '';
@@ -0,0 +1,91 @@
+package objectstore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkConditions проверяет условные заголовки по правилам RFC 9110:
+// If-Match важнее If-Unmodified-Since, If-None-Match важнее
+// If-Modified-Since.
+func checkConditions(info ObjectInfo, opts GetOptions) error {
+	modified := info.LastModified.Truncate(time.Second)
+
+	if opts.IfMatch != "" {
+		if !etagMatches(opts.IfMatch, info.ETag) {
+			return ErrPreconditionFailed
+		}
+	} else if !opts.IfUnmodifiedSince.IsZero() && modified.After(opts.IfUnmodifiedSince) {
+		return ErrPreconditionFailed
+	}
+
+	if opts.IfNoneMatch != "" {
+		if etagMatches(opts.IfNoneMatch, info.ETag) {
+			return ErrNotModified
+		}
+	} else if !opts.IfModifiedSince.IsZero() && !modified.After(opts.IfModifiedSince) {
+		return ErrNotModified
+	}
+	return nil
+}
+
+// etagMatches сравнивает ETag со списком из заголовка If-Match или
+// If-None-Match (слабое сравнение).
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange разбирает заголовок Range с одним диапазоном байт для
+// объекта размера size. Если заголовок пуст или содержит несколько
+// диапазонов, ok равно false и объект отдаётся целиком, как в S3.
+func parseRange(header string, size int64) (start, length int64, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, nil
+	}
+
+	if first == "" {
+		// bytes=-N — последние N байт
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false, ErrInvalidRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false, ErrInvalidRange
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, ErrInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end - start + 1, true, nil
+}
+
+func contentRange(start, length, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size)
+}
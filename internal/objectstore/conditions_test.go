@@ -0,0 +1,89 @@
+package objectstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header        string
+		size          int64
+		start, length int64
+		ok            bool
+		err           error
+	}{
+		{header: "", size: 100},
+		{header: "bytes=0-9", size: 100, start: 0, length: 10, ok: true},
+		{header: "bytes=90-", size: 100, start: 90, length: 10, ok: true},
+		{header: "bytes=90-200", size: 100, start: 90, length: 10, ok: true},
+		{header: "bytes=-10", size: 100, start: 90, length: 10, ok: true},
+		{header: "bytes=-500", size: 100, start: 0, length: 100, ok: true},
+		{header: "bytes= 5-5", size: 100, start: 5, length: 1, ok: true},
+		// Несколько диапазонов и другие единицы игнорируются.
+		{header: "bytes=0-1,5-6", size: 100},
+		{header: "items=0-1", size: 100},
+		{header: "bytes=5", size: 100},
+		{header: "bytes=100-", size: 100, err: ErrInvalidRange},
+		{header: "bytes=10-5", size: 100, err: ErrInvalidRange},
+		{header: "bytes=-0", size: 100, err: ErrInvalidRange},
+		{header: "bytes=-5", size: 0, err: ErrInvalidRange},
+		{header: "bytes=a-b", size: 100, err: ErrInvalidRange},
+		{header: "bytes=0-x", size: 100, err: ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		start, length, ok, err := parseRange(tt.header, tt.size)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseRange(%q, %d) error = %v, want %v", tt.header, tt.size, err, tt.err)
+			continue
+		}
+		if start != tt.start || length != tt.length || ok != tt.ok {
+			t.Errorf("parseRange(%q, %d) = %d, %d, %v, want %d, %d, %v",
+				tt.header, tt.size, start, length, ok, tt.start, tt.length, tt.ok)
+		}
+	}
+}
+
+func TestContentRange(t *testing.T) {
+	if got := contentRange(90, 10, 100); got != "bytes 90-99/100" {
+		t.Errorf("contentRange = %q", got)
+	}
+}
+
+func TestCheckConditions(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	info := ObjectInfo{ETag: `"abc"`, LastModified: modified}
+	before := modified.Add(-time.Hour)
+	after := modified.Add(time.Hour)
+	same := modified.Truncate(time.Second)
+
+	tests := []struct {
+		name string
+		opts GetOptions
+		want error
+	}{
+		{"no conditions", GetOptions{}, nil},
+		{"if-match hit", GetOptions{IfMatch: `"abc"`}, nil},
+		{"if-match list", GetOptions{IfMatch: `"x", "abc"`}, nil},
+		{"if-match star", GetOptions{IfMatch: "*"}, nil},
+		{"if-match weak", GetOptions{IfMatch: `W/"abc"`}, nil},
+		{"if-match miss", GetOptions{IfMatch: `"x"`}, ErrPreconditionFailed},
+		{"if-unmodified-since after", GetOptions{IfUnmodifiedSince: after}, nil},
+		{"if-unmodified-since same second", GetOptions{IfUnmodifiedSince: same}, nil},
+		{"if-unmodified-since before", GetOptions{IfUnmodifiedSince: before}, ErrPreconditionFailed},
+		{"if-match wins over if-unmodified-since", GetOptions{IfMatch: `"abc"`, IfUnmodifiedSince: before}, nil},
+		{"if-none-match hit", GetOptions{IfNoneMatch: `"abc"`}, ErrNotModified},
+		{"if-none-match star", GetOptions{IfNoneMatch: "*"}, ErrNotModified},
+		{"if-none-match miss", GetOptions{IfNoneMatch: `"x"`}, nil},
+		{"if-modified-since same second", GetOptions{IfModifiedSince: same}, ErrNotModified},
+		{"if-modified-since before", GetOptions{IfModifiedSince: before}, nil},
+		{"if-none-match wins over if-modified-since", GetOptions{IfNoneMatch: `"x"`, IfModifiedSince: after}, nil},
+		{"precondition checked first", GetOptions{IfMatch: `"x"`, IfNoneMatch: `"abc"`}, ErrPreconditionFailed},
+	}
+	for _, tt := range tests {
+		if err := checkConditions(info, tt.opts); !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkConditions = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStore хранит объекты в каталоге на диске: бакет — подкаталог
+// корня, ключ — относительный путь файла внутри бакета. Подходит для
+// разработки и тестов без внешнего хранилища.
+type LocalStore struct {
+	root string
+}
+
+// NewLocal создаёт LocalStore в каталоге root, создавая его при необходимости.
+func NewLocal(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("objectstore: create local root: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, bucket, key string, body io.Reader, opts PutOptions) error {
+	name, err := s.objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// Запись во временный файл и переименование, чтобы читатели
+	// не видели частично записанный объект.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, contextReader{ctx: ctx, r: body}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return err
+	}
+	return s.writeMeta(bucket, key, localMeta{
+		ContentType: opts.ContentType,
+		ACL:         opts.ACL,
+		Metadata:    opts.Metadata,
+		Tags:        opts.Tags,
+	})
+}
+
+func (s *LocalStore) Get(ctx context.Context, bucket, key string, opts GetOptions) (*Object, error) {
+	name, err := s.objectPath(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, s.mapError(bucket, err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.IsDir() {
+		f.Close()
+		return nil, ErrNotFound
+	}
+
+	obj := &Object{ObjectInfo: localInfo(key, fi), Body: f, ContentLength: fi.Size()}
+	if err := s.applyMeta(bucket, &obj.ObjectInfo); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := checkConditions(obj.ObjectInfo, opts); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	start, length, ok, err := parseRange(opts.Range, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if ok {
+		obj.Body = sectionReadCloser{io.NewSectionReader(f, start, length), f}
+		obj.ContentLength = length
+		obj.ContentRange = contentRange(start, length, fi.Size())
+	}
+	return obj, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, bucket, key string) error {
+	name, err := s.objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil {
+		err = s.mapError(bucket, err)
+		// Удаление несуществующего объекта, как и в S3, не ошибка.
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	s.removeMeta(bucket, key)
+	s.pruneDirs(bucket, filepath.Dir(name))
+	return nil
+}
+
+func (s *LocalStore) DeleteMany(ctx context.Context, bucket string, keys []string) ([]DeleteError, error) {
+	if _, err := s.bucketPath(bucket); err != nil {
+		return nil, err
+	}
+	var failed []DeleteError
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return failed, err
+		}
+		if err := s.Delete(ctx, bucket, key); err != nil {
+			if errors.Is(err, ErrBucketNotFound) {
+				return failed, err
+			}
+			code := "InternalError"
+			if errors.Is(err, ErrInvalidKey) {
+				code = "InvalidArgument"
+			}
+			failed = append(failed, DeleteError{Key: key, Code: code, Message: err.Error()})
+		}
+	}
+	return failed, nil
+}
+
+// List обходит каталог бакета целиком и отдаёт страницу после ключа,
+// закодированного в ContinuationToken.
+func (s *LocalStore) List(ctx context.Context, bucket string, opts ListOptions) (*ListResult, error) {
+	dir, err := s.bucketPath(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	startAfter := ""
+	if opts.ContinuationToken != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(opts.ContinuationToken)
+		if err != nil {
+			return nil, fmt.Errorf("%w: continuation token", ErrInvalidKey)
+		}
+		startAfter = string(raw)
+	}
+	maxKeys := opts.MaxKeys
+	if maxKeys <= 0 || maxKeys > MaxListKeys {
+		maxKeys = MaxListKeys
+	}
+
+	var objects []ObjectInfo
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, opts.Prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, localInfo(key, fi))
+		return nil
+	})
+	if err != nil {
+		return nil, s.mapError(bucket, err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	result := &ListResult{}
+	last := ""
+	for _, obj := range objects {
+		entry, isPrefix := obj.Key, false
+		if opts.Delimiter != "" {
+			rest := strings.TrimPrefix(obj.Key, opts.Prefix)
+			if i := strings.Index(rest, opts.Delimiter); i >= 0 {
+				entry, isPrefix = opts.Prefix+rest[:i+len(opts.Delimiter)], true
+			}
+		}
+		// Пропуск уже отданных записей, включая содержимое отданной «папки».
+		if entry <= startAfter || entry == last {
+			continue
+		}
+		if len(result.Objects)+len(result.CommonPrefixes) == maxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+			break
+		}
+		if isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, entry)
+		} else {
+			result.Objects = append(result.Objects, obj)
+		}
+		last = entry
+	}
+	return result, nil
+}
+
+func (s *LocalStore) Head(ctx context.Context, bucket, key string) (*ObjectInfo, error) {
+	name, err := s.objectPath(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, s.mapError(bucket, err)
+	}
+	if fi.IsDir() {
+		return nil, ErrNotFound
+	}
+	info := localInfo(key, fi)
+	if err := s.applyMeta(bucket, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (s *LocalStore) bucketPath(bucket string) (string, error) {
+	if bucket == "" || strings.ContainsAny(bucket, `/\`) || strings.HasPrefix(bucket, ".") {
+		return "", fmt.Errorf("%w: bucket %q", ErrInvalidKey, bucket)
+	}
+	return filepath.Join(s.root, bucket), nil
+}
+
+// objectPath возвращает путь файла объекта, не допуская выхода за
+// пределы каталога бакета.
+func (s *LocalStore) objectPath(bucket, key string) (string, error) {
+	dir, err := s.bucketPath(bucket)
+	if err != nil {
+		return "", err
+	}
+	clean := path.Clean("/" + key)
+	if key == "" || clean != "/"+key {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, part := range strings.Split(clean, "/") {
+		if strings.HasPrefix(part, ".upload-") {
+			return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(clean)), nil
+}
+
+// mapError приводит отсутствие файла к ErrNotFound или ErrBucketNotFound.
+func (s *LocalStore) mapError(bucket string, err error) error {
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if _, statErr := os.Stat(filepath.Join(s.root, bucket)); errors.Is(statErr, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrBucketNotFound, bucket)
+	}
+	return fmt.Errorf("%w: %v", ErrNotFound, err)
+}
+
+// pruneDirs удаляет опустевшие каталоги между dir и каталогом бакета.
+func (s *LocalStore) pruneDirs(bucket, dir string) {
+	bucketDir := filepath.Join(s.root, bucket)
+	for dir != bucketDir && strings.HasPrefix(dir, bucketDir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func localInfo(key string, fi fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          key,
+		Size:         fi.Size(),
+		LastModified: fi.ModTime().UTC(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		ETag:         fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+	}
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+	io.Closer
+}
+
+// contextReader прерывает чтение после отмены контекста.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
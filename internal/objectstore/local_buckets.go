@@ -0,0 +1,74 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (s *LocalStore) CreateBucket(ctx context.Context, bucket string) error {
+	dir, err := s.bucketPath(bucket)
+	if err != nil {
+		return err
+	}
+	err = os.Mkdir(dir, 0o755)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrBucketExists, bucket)
+	}
+	return err
+}
+
+func (s *LocalStore) DeleteBucket(ctx context.Context, bucket string) error {
+	dir, err := s.bucketPath(bucket)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return s.mapError(bucket, err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%w: %s", ErrBucketNotEmpty, bucket)
+	}
+	if err := os.Remove(dir); err != nil {
+		return s.mapError(bucket, err)
+	}
+	return os.RemoveAll(filepath.Join(s.root, metaDir, bucket))
+}
+
+func (s *LocalStore) HeadBucket(ctx context.Context, bucket string) error {
+	dir, err := s.bucketPath(bucket)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(dir)
+	if err != nil || !fi.IsDir() {
+		return fmt.Errorf("%w: %s", ErrBucketNotFound, bucket)
+	}
+	return nil
+}
+
+// ListBuckets возвращает подкаталоги корня. Каталоги, начинающиеся с
+// точки, служебные и бакетами не считаются.
+func (s *LocalStore) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+	buckets := []BucketInfo{}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, BucketInfo{Name: e.Name(), CreatedAt: fi.ModTime().UTC()})
+	}
+	return buckets, nil
+}
@@ -0,0 +1,147 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metaDir — служебный каталог в корне LocalStore с тем, чего нет в
+// самом файле: типом содержимого, ACL, метаданными и тегами объекта. Для
+// объекта key бакета bucket это файл .meta/bucket/key.json.
+const metaDir = ".meta"
+
+type localMeta struct {
+	ContentType string            `json:"content_type,omitempty"`
+	ACL         string            `json:"acl,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+func (s *LocalStore) metaPath(bucket, key string) string {
+	return filepath.Join(s.root, metaDir, bucket, filepath.FromSlash(key)) + ".json"
+}
+
+// writeMeta сохраняет сведения об объекте. Если сохранять нечего,
+// прежние сведения удаляются: Put заменяет объект целиком.
+func (s *LocalStore) writeMeta(bucket, key string, meta localMeta) error {
+	if meta.ContentType == "" && meta.ACL == "" && len(meta.Metadata) == 0 && len(meta.Tags) == 0 {
+		s.removeMeta(bucket, key)
+		return nil
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	name := s.metaPath(bucket, key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0o644)
+}
+
+func (s *LocalStore) readMeta(bucket, key string) (localMeta, error) {
+	var meta localMeta
+	data, err := os.ReadFile(s.metaPath(bucket, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, err
+	}
+	return meta, json.Unmarshal(data, &meta)
+}
+
+// removeMeta удаляет сведения об объекте и опустевшие каталоги над ними.
+func (s *LocalStore) removeMeta(bucket, key string) {
+	name := s.metaPath(bucket, key)
+	if os.Remove(name) != nil {
+		return
+	}
+	bucketDir := filepath.Join(s.root, metaDir, bucket)
+	for dir := filepath.Dir(name); dir != bucketDir && strings.HasPrefix(dir, bucketDir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// applyMeta дополняет info сохранёнными типом содержимого и метаданными.
+func (s *LocalStore) applyMeta(bucket string, info *ObjectInfo) error {
+	meta, err := s.readMeta(bucket, info.Key)
+	if err != nil {
+		return err
+	}
+	if meta.ContentType != "" {
+		info.ContentType = meta.ContentType
+	}
+	info.Metadata = meta.Metadata
+	return nil
+}
+
+func (s *LocalStore) Tags(ctx context.Context, bucket, key string) (map[string]string, error) {
+	if _, err := s.Head(ctx, bucket, key); err != nil {
+		return nil, err
+	}
+	meta, err := s.readMeta(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Tags == nil {
+		return map[string]string{}, nil
+	}
+	return meta.Tags, nil
+}
+
+// ACL возвращает сохранённый при загрузке или через SetACL ACL объекта.
+// Локальные файлы доступны только через сервис, ACL лишь хранится.
+func (s *LocalStore) ACL(ctx context.Context, bucket, key string) (string, error) {
+	if _, err := s.Head(ctx, bucket, key); err != nil {
+		return "", err
+	}
+	meta, err := s.readMeta(bucket, key)
+	if err != nil {
+		return "", err
+	}
+	if meta.ACL == "" {
+		return ACLPrivate, nil
+	}
+	return meta.ACL, nil
+}
+
+func (s *LocalStore) SetACL(ctx context.Context, bucket, key, acl string) error {
+	if _, err := s.Head(ctx, bucket, key); err != nil {
+		return err
+	}
+	meta, err := s.readMeta(bucket, key)
+	if err != nil {
+		return err
+	}
+	meta.ACL = acl
+	return s.writeMeta(bucket, key, meta)
+}
+
+// Copy копирует файл объекта вместе с сохранёнными о нём сведениями.
+func (s *LocalStore) Copy(ctx context.Context, src, dst ObjectRef, acl string) error {
+	obj, err := s.Get(ctx, src.Bucket, src.Key, GetOptions{})
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+
+	meta, err := s.readMeta(src.Bucket, src.Key)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, dst.Bucket, dst.Key, obj.Body, PutOptions{
+		ContentType: meta.ContentType,
+		ACL:         acl,
+		Metadata:    meta.Metadata,
+		Tags:        meta.Tags,
+	})
+}
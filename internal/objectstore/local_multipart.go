@@ -0,0 +1,144 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// multipartDir — служебный каталог незавершённых загрузок в корне LocalStore.
+const multipartDir = ".multipart"
+
+type localUpload struct {
+	Bucket  string     `json:"bucket"`
+	Key     string     `json:"key"`
+	Options PutOptions `json:"options"`
+}
+
+func (s *LocalStore) CreateMultipartUpload(ctx context.Context, bucket, key string, opts PutOptions) (string, error) {
+	if _, err := s.objectPath(bucket, key); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(buf)
+
+	dir := filepath.Join(s.root, multipartDir, uploadID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	meta, err := json.Marshal(localUpload{Bucket: bucket, Key: key, Options: opts})
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "upload.json"), meta, 0o644); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+func (s *LocalStore) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int, body io.Reader, size int64) (string, error) {
+	dir, err := s.uploadDir(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".part-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), contextReader{ctx: ctx, r: body})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", fmt.Errorf("objectstore: part %d: got %d bytes, want %d", partNumber, n, size)
+	}
+
+	if err := os.Rename(tmp.Name(), partPath(dir, partNumber)); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`, nil
+}
+
+func (s *LocalStore) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) error {
+	dir, upload, err := s.upload(bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	readers := make([]io.Reader, 0, len(parts))
+	for _, p := range parts {
+		f, err := os.Open(partPath(dir, p.PartNumber))
+		if err != nil {
+			return fmt.Errorf("objectstore: part %d: %w", p.PartNumber, err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	if err := s.Put(ctx, bucket, key, io.MultiReader(readers...), upload.Options); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func (s *LocalStore) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	dir, err := s.uploadDir(bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// uploadDir возвращает каталог загрузки uploadID, проверяя, что она
+// начата для того же объекта.
+func (s *LocalStore) uploadDir(bucket, key, uploadID string) (string, error) {
+	dir, _, err := s.upload(bucket, key, uploadID)
+	return dir, err
+}
+
+// upload возвращает каталог и параметры загрузки uploadID.
+func (s *LocalStore) upload(bucket, key, uploadID string) (string, *localUpload, error) {
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", nil, fmt.Errorf("%w: %s", ErrUploadNotFound, uploadID)
+	}
+	dir := filepath.Join(s.root, multipartDir, uploadID)
+
+	data, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil, fmt.Errorf("%w: %s", ErrUploadNotFound, uploadID)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	var meta localUpload
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", nil, err
+	}
+	if meta.Bucket != bucket || meta.Key != key {
+		return "", nil, fmt.Errorf("%w: %s", ErrUploadNotFound, uploadID)
+	}
+	return dir, &meta, nil
+}
+
+func partPath(dir string, partNumber int) string {
+	return filepath.Join(dir, fmt.Sprintf("part-%05d", partNumber))
+}
@@ -0,0 +1,225 @@
+// Package objectstore описывает хранилище объектов, с которым работают
+// обработчики сервиса, и его реализации: S3-совместимое хранилище (в том
+// числе CLO) и каталог на локальном диске.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound возвращается, если объект не существует.
+	ErrNotFound = errors.New("objectstore: object not found")
+	// ErrBucketNotFound возвращается, если бакет не существует.
+	ErrBucketNotFound = errors.New("objectstore: bucket not found")
+	// ErrInvalidKey возвращается для ключей, которые нельзя сохранить.
+	ErrInvalidKey = errors.New("objectstore: invalid key")
+	// ErrUploadNotFound возвращается для неизвестной или уже завершённой
+	// multipart-загрузки.
+	ErrUploadNotFound = errors.New("objectstore: multipart upload not found")
+	// ErrNotModified возвращается, если объект не изменился по условиям
+	// If-None-Match или If-Modified-Since.
+	ErrNotModified = errors.New("objectstore: not modified")
+	// ErrPreconditionFailed возвращается, если не выполнено условие
+	// If-Match или If-Unmodified-Since.
+	ErrPreconditionFailed = errors.New("objectstore: precondition failed")
+	// ErrInvalidRange возвращается, если диапазон не пересекается с объектом.
+	ErrInvalidRange = errors.New("objectstore: invalid range")
+	// ErrBucketExists возвращается при создании существующего бакета.
+	ErrBucketExists = errors.New("objectstore: bucket already exists")
+	// ErrBucketNotEmpty возвращается при удалении бакета с объектами.
+	ErrBucketNotEmpty = errors.New("objectstore: bucket not empty")
+)
+
+// Store — хранилище объектов, разложенных по бакетам.
+type Store interface {
+	Put(ctx context.Context, bucket, key string, body io.Reader, opts PutOptions) error
+	Get(ctx context.Context, bucket, key string, opts GetOptions) (*Object, error)
+	Delete(ctx context.Context, bucket, key string) error
+	DeleteMany(ctx context.Context, bucket string, keys []string) ([]DeleteError, error)
+	Copy(ctx context.Context, src, dst ObjectRef, acl string) error
+	List(ctx context.Context, bucket string, opts ListOptions) (*ListResult, error)
+	Head(ctx context.Context, bucket, key string) (*ObjectInfo, error)
+	Tags(ctx context.Context, bucket, key string) (map[string]string, error)
+	ACL(ctx context.Context, bucket, key string) (string, error)
+	SetACL(ctx context.Context, bucket, key, acl string) error
+
+	Buckets
+	Multipart
+}
+
+// Buckets — управление бакетами. DeleteBucket удаляет только пустой бакет.
+type Buckets interface {
+	CreateBucket(ctx context.Context, bucket string) error
+	DeleteBucket(ctx context.Context, bucket string) error
+	HeadBucket(ctx context.Context, bucket string) error
+	ListBuckets(ctx context.Context) ([]BucketInfo, error)
+}
+
+// BucketInfo — сведения о бакете.
+type BucketInfo struct {
+	Name      string
+	CreatedAt time.Time
+}
+
+// ValidBucketName проверяет имя бакета по правилам S3: от 3 до 63
+// символов, строчные латинские буквы, цифры, точки и дефисы, первый и
+// последний символ — буква или цифра.
+func ValidBucketName(name string) bool {
+	if len(name) < 3 || len(name) > 63 {
+		return false
+	}
+	for i, c := range name {
+		alnum := c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+		if (i == 0 || i == len(name)-1) && !alnum {
+			return false
+		}
+		if !alnum && c != '-' && c != '.' {
+			return false
+		}
+	}
+	return !strings.Contains(name, "..")
+}
+
+// Multipart — явное управление multipart-загрузкой, когда части
+// приходят в разных запросах. Все части, кроме последней, должны быть
+// не меньше MinPartSize.
+type Multipart interface {
+	CreateMultipartUpload(ctx context.Context, bucket, key string, opts PutOptions) (string, error)
+	UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int, body io.Reader, size int64) (string, error)
+	CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) error
+	AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error
+}
+
+// CompletedPart — загруженная часть multipart-загрузки.
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+const (
+	// MinPartSize — минимальный размер части, кроме последней.
+	MinPartSize = 5 << 20
+	// MaxPartSize — максимальный размер одной части.
+	MaxPartSize = 5 << 30
+	// MaxParts — максимальное число частей одной загрузки.
+	MaxParts = 10000
+)
+
+// Готовые (canned) ACL объекта. Пустой ACL в PutOptions — ACL бакета,
+// обычно ACLPrivate.
+const (
+	ACLPrivate           = "private"
+	ACLPublicRead        = "public-read"
+	ACLPublicReadWrite   = "public-read-write"
+	ACLAuthenticatedRead = "authenticated-read"
+)
+
+// PutOptions — параметры сохранения объекта. Metadata — пользовательские
+// метаданные (x-amz-meta-*), Tags — теги объекта.
+type PutOptions struct {
+	ContentType string
+	ACL         string
+	Metadata    map[string]string
+	Tags        map[string]string
+}
+
+// GetOptions — диапазон и условия чтения объекта в формате заголовков
+// HTTP Range, If-Match, If-None-Match, If-Modified-Since и
+// If-Unmodified-Since. Пустые поля не проверяются.
+type GetOptions struct {
+	Range             string
+	IfMatch           string
+	IfNoneMatch       string
+	IfModifiedSince   time.Time
+	IfUnmodifiedSince time.Time
+}
+
+// MaxDeleteKeys — наибольшее число ключей в одном запросе
+// DeleteObjects; DeleteMany делит список на части такого размера.
+const MaxDeleteKeys = 1000
+
+// ObjectRef — объект key в бакете Bucket.
+type ObjectRef struct {
+	Bucket string
+	Key    string
+}
+
+// MaxCopySize — наибольший объект, который хранилище копирует одним
+// запросом; объекты больше копируются по частям.
+const MaxCopySize = 5 << 30
+
+// DeleteError — ключ, который DeleteMany не смог удалить, с кодом и
+// описанием ошибки хранилища.
+type DeleteError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+// MaxListKeys — наибольшее число записей на одной странице списка.
+const MaxListKeys = 1000
+
+// ListOptions — параметры постраничного списка объектов. Если задан
+// Delimiter, ключи с ним после Prefix сворачиваются в «папки»
+// (CommonPrefixes). ContinuationToken — непрозрачный маркер следующей
+// страницы из предыдущего ListResult.
+type ListOptions struct {
+	Prefix            string
+	Delimiter         string
+	MaxKeys           int
+	ContinuationToken string
+}
+
+// ListResult — страница списка объектов.
+type ListResult struct {
+	Objects               []ObjectInfo
+	CommonPrefixes        []string
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// ListAll обходит все страницы списка и собирает их в один результат.
+func ListAll(ctx context.Context, s Store, bucket string, opts ListOptions) (*ListResult, error) {
+	all := &ListResult{}
+	opts.MaxKeys = MaxListKeys
+	for {
+		page, err := s.List(ctx, bucket, opts)
+		if err != nil {
+			return nil, err
+		}
+		all.Objects = append(all.Objects, page.Objects...)
+		all.CommonPrefixes = append(all.CommonPrefixes, page.CommonPrefixes...)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return all, nil
+		}
+		opts.ContinuationToken = page.NextContinuationToken
+	}
+}
+
+// ObjectInfo — сведения об объекте без его содержимого. Metadata
+// заполняется только при чтении одного объекта (Head, Get), не в List.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	ContentType  string
+	ETag         string
+	StorageClass string
+	Metadata     map[string]string
+}
+
+// Object — объект вместе с потоком содержимого. Body нужно закрыть.
+// Size — размер всего объекта; если запрошен диапазон, ContentRange
+// содержит его в формате заголовка Content-Range, а ContentLength —
+// длину переданной части.
+type Object struct {
+	ObjectInfo
+	Body          io.ReadCloser
+	ContentLength int64
+	ContentRange  string
+}
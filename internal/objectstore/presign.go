@@ -0,0 +1,144 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Presigner выдаёт ссылки, по которым клиент обращается к хранилищу
+// напрямую, минуя сервис. LocalStore его не реализует.
+type Presigner interface {
+	PresignGet(ctx context.Context, bucket, key string, expires time.Duration) (string, error)
+	PresignPut(ctx context.Context, bucket, key string, expires time.Duration, opts PutOptions) (string, error)
+	PresignPost(ctx context.Context, bucket, key string, expires time.Duration, cond PostConditions) (*PresignedPost, error)
+}
+
+// PostConditions — ограничения политики браузерной загрузки через POST.
+// ContentType, оканчивающийся на "/", задаёт префикс (например "image/").
+type PostConditions struct {
+	MinSize     int64
+	MaxSize     int64
+	ContentType string
+	ACL         string
+}
+
+// PresignedPost — адрес и поля формы для загрузки через POST.
+// Файл передаётся последним полем формы с именем "file".
+type PresignedPost struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
+
+func (s *S3Store) PresignGet(ctx context.Context, bucket, key string, expires time.Duration) (string, error) {
+	req, err := s3.NewPresignClient(s.client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (s *S3Store) PresignPut(ctx context.Context, bucket, key string, expires time.Duration, opts PutOptions) (string, error) {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.ACL != "" {
+		input.ACL = types.ObjectCannedACL(opts.ACL)
+	}
+	req, err := s3.NewPresignClient(s.client).PresignPutObject(ctx, input, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+// PresignPost формирует политику POST-загрузки и подписывает её по
+// схеме AWS Signature Version 4.
+func (s *S3Store) PresignPost(ctx context.Context, bucket, key string, expires time.Duration, cond PostConditions) (*PresignedPost, error) {
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	credential := strings.Join([]string{s.cfg.AccessKey, date, s.cfg.Region, "s3", "aws4_request"}, "/")
+
+	fields := map[string]string{
+		"key":              key,
+		"x-amz-algorithm":  "AWS4-HMAC-SHA256",
+		"x-amz-credential": credential,
+		"x-amz-date":       now.Format("20060102T150405Z"),
+	}
+	conditions := []interface{}{
+		map[string]string{"bucket": bucket},
+		map[string]string{"key": key},
+		map[string]string{"x-amz-algorithm": fields["x-amz-algorithm"]},
+		map[string]string{"x-amz-credential": credential},
+		map[string]string{"x-amz-date": fields["x-amz-date"]},
+	}
+	if cond.MaxSize > 0 {
+		conditions = append(conditions, []interface{}{"content-length-range", cond.MinSize, cond.MaxSize})
+	}
+	if cond.ContentType != "" {
+		if strings.HasSuffix(cond.ContentType, "/") {
+			conditions = append(conditions, []interface{}{"starts-with", "$Content-Type", cond.ContentType})
+		} else {
+			fields["Content-Type"] = cond.ContentType
+			conditions = append(conditions, map[string]string{"Content-Type": cond.ContentType})
+		}
+	}
+	if cond.ACL != "" {
+		fields["acl"] = cond.ACL
+		conditions = append(conditions, map[string]string{"acl": cond.ACL})
+	}
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(expires).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fields["policy"] = base64.StdEncoding.EncodeToString(policy)
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	for _, part := range []string{s.cfg.Region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(signingKey, fields["policy"]))
+
+	return &PresignedPost{URL: s.bucketURL(bucket), Fields: fields}, nil
+}
+
+// bucketURL возвращает адрес бакета для POST-загрузки.
+func (s *S3Store) bucketURL(bucket string) string {
+	u, err := url.Parse(s.cfg.Endpoint)
+	if err != nil || u.Host == "" {
+		return strings.TrimRight(s.cfg.Endpoint, "/") + "/" + bucket
+	}
+	if s.cfg.ForcePathStyle {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + bucket
+	} else {
+		u.Host = bucket + "." + u.Host
+	}
+	return u.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
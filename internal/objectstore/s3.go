@@ -0,0 +1,495 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// CLOEndpoint — адрес S3 API хранилища CLO.
+const CLOEndpoint = "https://storage.clo.ru"
+
+// S3Config — параметры подключения к S3-совместимому хранилищу.
+type S3Config struct {
+	Endpoint       string
+	Region         string
+	AccessKey      string
+	SecretKey      string
+	ForcePathStyle bool
+
+	// PartSize и Concurrency задают размер части и число частей,
+	// загружаемых параллельно. Нулевые значения — умолчания SDK.
+	PartSize    int64
+	Concurrency int
+
+	// HTTPClient — клиент для запросов к хранилищу; по нему несколько
+	// S3Store могут делить один пул соединений. nil — клиент SDK.
+	HTTPClient *http.Client
+
+	// Retry — повторы неудачных запросов.
+	Retry RetryConfig
+
+	// APIOptions добавляют обработчики в стек middleware каждого
+	// запроса, например RequestLogger.
+	APIOptions []func(*middleware.Stack) error
+}
+
+// S3Store хранит объекты в S3-совместимом хранилище.
+type S3Store struct {
+	client *s3.Client
+	cfg    S3Config
+}
+
+// NewS3 создаёт S3Store по параметрам cfg. Адрес хранилища задаётся
+// Endpoint, а не регионом AWS: см. endpointResolver.
+func NewS3(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("objectstore: endpoint is required")
+	}
+	resolver, err := newEndpointResolver(cfg.Endpoint, cfg.ForcePathStyle)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.New(s3.Options{
+		Region:             cfg.Region,
+		Credentials:        credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, ""),
+		EndpointResolverV2: resolver,
+		UsePathStyle:       cfg.ForcePathStyle,
+		Retryer:            cfg.Retry.retryer(),
+		APIOptions:         cfg.APIOptions,
+	}, func(o *s3.Options) {
+		if cfg.HTTPClient != nil {
+			o.HTTPClient = cfg.HTTPClient
+		}
+	})
+	return &S3Store{client: client, cfg: cfg}, nil
+}
+
+// Put загружает body потоком: тело делится на части, которые
+// отправляются через multipart upload. При ошибке или отмене ctx
+// незавершённая загрузка удаляется в хранилище.
+func (s *S3Store) Put(ctx context.Context, bucket, key string, body io.Reader, opts PutOptions) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.ACL != "" {
+		input.ACL = types.ObjectCannedACL(opts.ACL)
+	}
+	if len(opts.Metadata) > 0 {
+		input.Metadata = opts.Metadata
+	}
+	if len(opts.Tags) > 0 {
+		input.Tagging = aws.String(encodeTags(opts.Tags))
+	}
+
+	uploader := manager.NewUploader(s.client, func(u *manager.Uploader) {
+		if s.cfg.PartSize > 0 {
+			u.PartSize = s.cfg.PartSize
+		}
+		if s.cfg.Concurrency > 0 {
+			u.Concurrency = s.cfg.Concurrency
+		}
+		u.LeavePartsOnError = false
+	})
+	if _, err := uploader.Upload(ctx, input); err != nil {
+		return mapS3Error(err)
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, bucket, key string, opts GetOptions) (*Object, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if opts.Range != "" {
+		input.Range = aws.String(opts.Range)
+	}
+	if opts.IfMatch != "" {
+		input.IfMatch = aws.String(opts.IfMatch)
+	}
+	if opts.IfNoneMatch != "" {
+		input.IfNoneMatch = aws.String(opts.IfNoneMatch)
+	}
+	if !opts.IfModifiedSince.IsZero() {
+		input.IfModifiedSince = aws.Time(opts.IfModifiedSince)
+	}
+	if !opts.IfUnmodifiedSince.IsZero() {
+		input.IfUnmodifiedSince = aws.Time(opts.IfUnmodifiedSince)
+	}
+
+	output, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	obj := &Object{
+		ObjectInfo: ObjectInfo{
+			Key:          key,
+			Size:         aws.ToInt64(output.ContentLength),
+			LastModified: aws.ToTime(output.LastModified),
+			ContentType:  aws.ToString(output.ContentType),
+			ETag:         aws.ToString(output.ETag),
+			StorageClass: string(output.StorageClass),
+			Metadata:     output.Metadata,
+		},
+		Body:          output.Body,
+		ContentLength: aws.ToInt64(output.ContentLength),
+		ContentRange:  aws.ToString(output.ContentRange),
+	}
+	// Для диапазона полный размер объекта указан после "/" в Content-Range.
+	if _, total, ok := strings.Cut(obj.ContentRange, "/"); ok {
+		if size, err := strconv.ParseInt(total, 10, 64); err == nil {
+			obj.Size = size
+		}
+	}
+	return obj, nil
+}
+
+// deleteWaitTimeout — сколько Delete ждёт, пока объект перестанет
+// находиться в хранилище.
+const deleteWaitTimeout = 2 * time.Minute
+
+func (s *S3Store) Delete(ctx context.Context, bucket, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return mapS3Error(err)
+	}
+
+	// Ожидание завершения удаления
+	err = s3.NewObjectNotExistsWaiter(s.client).Wait(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}, deleteWaitTimeout)
+	if err != nil {
+		return mapS3Error(err)
+	}
+	return nil
+}
+
+// DeleteMany удаляет ключи запросами DeleteObjects по MaxDeleteKeys
+// ключей, не дожидаясь, пока объекты исчезнут. Отсутствующие ключи, как
+// и в Delete, ошибкой не считаются. Ошибка возвращается, только если
+// хранилище отклонило запрос целиком; ключи предыдущих частей к этому
+// моменту уже удалены.
+func (s *S3Store) DeleteMany(ctx context.Context, bucket string, keys []string) ([]DeleteError, error) {
+	var failed []DeleteError
+	for start := 0; start < len(keys); start += MaxDeleteKeys {
+		chunk := keys[start:min(start+MaxDeleteKeys, len(keys))]
+		objects := make([]types.ObjectIdentifier, 0, len(chunk))
+		for _, key := range chunk {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		output, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return failed, mapS3Error(err)
+		}
+		for _, e := range output.Errors {
+			failed = append(failed, DeleteError{
+				Key:     aws.ToString(e.Key),
+				Code:    aws.ToString(e.Code),
+				Message: aws.ToString(e.Message),
+			})
+		}
+	}
+	return failed, nil
+}
+
+func (s *S3Store) List(ctx context.Context, bucket string, opts ListOptions) (*ListResult, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	}
+	if opts.Prefix != "" {
+		input.Prefix = aws.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+	if opts.MaxKeys > 0 {
+		input.MaxKeys = aws.Int32(int32(opts.MaxKeys))
+	}
+	if opts.ContinuationToken != "" {
+		input.ContinuationToken = aws.String(opts.ContinuationToken)
+	}
+
+	resp, err := s.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	result := &ListResult{
+		Objects:               make([]ObjectInfo, 0, len(resp.Contents)),
+		IsTruncated:           aws.ToBool(resp.IsTruncated),
+		NextContinuationToken: aws.ToString(resp.NextContinuationToken),
+	}
+	for _, item := range resp.Contents {
+		result.Objects = append(result.Objects, ObjectInfo{
+			Key:          aws.ToString(item.Key),
+			Size:         aws.ToInt64(item.Size),
+			LastModified: aws.ToTime(item.LastModified),
+			ETag:         aws.ToString(item.ETag),
+			StorageClass: string(item.StorageClass),
+		})
+	}
+	for _, p := range resp.CommonPrefixes {
+		result.CommonPrefixes = append(result.CommonPrefixes, aws.ToString(p.Prefix))
+	}
+	return result, nil
+}
+
+func (s *S3Store) Head(ctx context.Context, bucket, key string) (*ObjectInfo, error) {
+	output, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		LastModified: aws.ToTime(output.LastModified),
+		ContentType:  aws.ToString(output.ContentType),
+		ETag:         aws.ToString(output.ETag),
+		StorageClass: string(output.StorageClass),
+		Metadata:     output.Metadata,
+	}, nil
+}
+
+func (s *S3Store) Tags(ctx context.Context, bucket, key string) (map[string]string, error) {
+	output, err := s.client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+
+	tags := make(map[string]string, len(output.TagSet))
+	for _, t := range output.TagSet {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	return tags, nil
+}
+
+// Группы получателей прав в ACL S3.
+const (
+	allUsersURI           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	authenticatedUsersURI = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// ACL сводит права объекта к готовому ACL. Права, выданные отдельным
+// пользователям, не учитываются.
+func (s *S3Store) ACL(ctx context.Context, bucket, key string) (string, error) {
+	output, err := s.client.GetObjectAcl(ctx, &s3.GetObjectAclInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", mapS3Error(err)
+	}
+
+	var publicRead, publicWrite, authenticatedRead bool
+	for _, g := range output.Grants {
+		if g.Grantee == nil || g.Grantee.Type != types.TypeGroup {
+			continue
+		}
+		read := g.Permission == types.PermissionRead || g.Permission == types.PermissionFullControl
+		write := g.Permission == types.PermissionWrite || g.Permission == types.PermissionFullControl
+		switch aws.ToString(g.Grantee.URI) {
+		case allUsersURI:
+			publicRead = publicRead || read
+			publicWrite = publicWrite || write
+		case authenticatedUsersURI:
+			authenticatedRead = authenticatedRead || read
+		}
+	}
+
+	switch {
+	case publicRead && publicWrite:
+		return ACLPublicReadWrite, nil
+	case publicRead:
+		return ACLPublicRead, nil
+	case authenticatedRead:
+		return ACLAuthenticatedRead, nil
+	}
+	return ACLPrivate, nil
+}
+
+func (s *S3Store) SetACL(ctx context.Context, bucket, key, acl string) error {
+	_, err := s.client.PutObjectAcl(ctx, &s3.PutObjectAclInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		ACL:    types.ObjectCannedACL(acl),
+	})
+	if err != nil {
+		return mapS3Error(err)
+	}
+	return nil
+}
+
+// encodeTags кодирует теги в формат заголовка x-amz-tagging.
+func encodeTags(tags map[string]string) string {
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// mapS3Error приводит ошибки SDK к ошибкам пакета: отсутствие объекта,
+// бакета или загрузки, конфликты бакетов и невыполненные условия запроса.
+func mapS3Error(err error) error {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.HTTPStatusCode() {
+		case http.StatusNotModified:
+			return fmt.Errorf("%w: %v", ErrNotModified, err)
+		case http.StatusPreconditionFailed:
+			return fmt.Errorf("%w: %v", ErrPreconditionFailed, err)
+		case http.StatusRequestedRangeNotSatisfiable:
+			return fmt.Errorf("%w: %v", ErrInvalidRange, err)
+		}
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.ErrorCode() {
+	case "NoSuchKey", "NotFound":
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	case "NoSuchBucket":
+		return fmt.Errorf("%w: %v", ErrBucketNotFound, err)
+	case "NoSuchUpload":
+		return fmt.Errorf("%w: %v", ErrUploadNotFound, err)
+	case "BucketAlreadyExists", "BucketAlreadyOwnedByYou":
+		return fmt.Errorf("%w: %v", ErrBucketExists, err)
+	case "BucketNotEmpty":
+		return fmt.Errorf("%w: %v", ErrBucketNotEmpty, err)
+	}
+	return err
+}
+
+func (s *S3Store) CreateMultipartUpload(ctx context.Context, bucket, key string, opts PutOptions) (string, error) {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.ACL != "" {
+		input.ACL = types.ObjectCannedACL(opts.ACL)
+	}
+	if len(opts.Metadata) > 0 {
+		input.Metadata = opts.Metadata
+	}
+	if len(opts.Tags) > 0 {
+		input.Tagging = aws.String(encodeTags(opts.Tags))
+	}
+
+	output, err := s.client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return "", mapS3Error(err)
+	}
+	return aws.ToString(output.UploadId), nil
+}
+
+// UploadPart сохраняет часть во временный файл: для подписи запроса
+// SDK нужно тело, которое можно перечитать.
+func (s *S3Store) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int, body io.Reader, size int64) (string, error) {
+	tmp, err := os.CreateTemp("", "s3part-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	n, err := io.Copy(tmp, contextReader{ctx: ctx, r: body})
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", fmt.Errorf("objectstore: part %d: got %d bytes, want %d", partNumber, n, size)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	output, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(key),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(int32(partNumber)),
+		Body:          tmp,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return "", mapS3Error(err)
+	}
+	return aws.ToString(output.ETag), nil
+}
+
+func (s *S3Store) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) error {
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, p := range parts {
+		completed = append(completed, types.CompletedPart{
+			PartNumber: aws.Int32(int32(p.PartNumber)),
+			ETag:       aws.String(p.ETag),
+		})
+	}
+
+	_, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(key),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return mapS3Error(err)
+	}
+	return nil
+}
+
+func (s *S3Store) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		return mapS3Error(err)
+	}
+	return nil
+}
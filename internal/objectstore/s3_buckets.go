@@ -0,0 +1,62 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func (s *S3Store) CreateBucket(ctx context.Context, bucket string) error {
+	_, err := s.client.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return mapS3Error(err)
+	}
+	return nil
+}
+
+func (s *S3Store) DeleteBucket(ctx context.Context, bucket string) error {
+	_, err := s.client.DeleteBucket(ctx, &s3.DeleteBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return mapS3Error(err)
+	}
+	return nil
+}
+
+// HeadBucket проверяет, что бакет существует и доступен с ключами
+// хранилища.
+func (s *S3Store) HeadBucket(ctx context.Context, bucket string) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err == nil {
+		return nil
+	}
+	// На HEAD хранилище отвечает без тела, поэтому код ошибки — "NotFound".
+	err = mapS3Error(err)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrBucketNotFound, bucket)
+	}
+	return err
+}
+
+func (s *S3Store) ListBuckets(ctx context.Context) ([]BucketInfo, error) {
+	resp, err := s.client.ListBuckets(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, mapS3Error(err)
+	}
+	buckets := make([]BucketInfo, 0, len(resp.Buckets))
+	for _, b := range resp.Buckets {
+		buckets = append(buckets, BucketInfo{
+			Name:      aws.ToString(b.Name),
+			CreatedAt: aws.ToTime(b.CreationDate),
+		})
+	}
+	return buckets, nil
+}
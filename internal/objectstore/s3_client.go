@@ -0,0 +1,94 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// endpointResolver направляет все запросы на адрес хранилища из
+// конфигурации вместо адресов AWS по региону. Бакет указывается в пути
+// (path style) или в имени хоста.
+type endpointResolver struct {
+	base      url.URL
+	pathStyle bool
+}
+
+func newEndpointResolver(endpoint string, pathStyle bool) (*endpointResolver, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("objectstore: parse endpoint: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("objectstore: endpoint %q must include scheme and host", endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return &endpointResolver{base: *u, pathStyle: pathStyle}, nil
+}
+
+func (r *endpointResolver) ResolveEndpoint(_ context.Context, params s3.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	u := r.base
+	if bucket := aws.ToString(params.Bucket); bucket != "" {
+		if r.pathStyle || aws.ToBool(params.ForcePathStyle) {
+			u.Path += "/" + bucket
+		} else {
+			u.Host = bucket + "." + u.Host
+		}
+	}
+	return smithyendpoints.Endpoint{URI: u}, nil
+}
+
+// RetryConfig — повторы запросов к хранилищу. Нулевые значения —
+// умолчания SDK (3 попытки, пауза до 20 секунд).
+type RetryConfig struct {
+	MaxAttempts int
+	MaxBackoff  time.Duration
+}
+
+func (c RetryConfig) retryer() aws.Retryer {
+	if c.MaxAttempts == 0 && c.MaxBackoff == 0 {
+		return nil
+	}
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		if c.MaxAttempts > 0 {
+			o.MaxAttempts = c.MaxAttempts
+		}
+		if c.MaxBackoff > 0 {
+			o.MaxBackoff = c.MaxBackoff
+		}
+	})
+}
+
+// RequestLogger пишет в logger каждую попытку запроса к хранилищу:
+// операцию, код ответа, длительность и ошибку.
+func RequestLogger(logger *log.Logger) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("RequestLogger",
+			func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+				start := time.Now()
+				out, metadata, err := next.HandleDeserialize(ctx, in)
+
+				status := 0
+				if resp, ok := out.RawResponse.(*smithyhttp.Response); ok {
+					status = resp.StatusCode
+				}
+				if err != nil {
+					logger.Printf("s3 %s: status %d, %s: %v", awsmiddleware.GetOperationName(ctx), status, time.Since(start), err)
+				} else {
+					logger.Printf("s3 %s: status %d, %s", awsmiddleware.GetOperationName(ctx), status, time.Since(start))
+				}
+				return out, metadata, err
+			}), middleware.Before)
+	}
+}
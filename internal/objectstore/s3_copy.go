@@ -0,0 +1,152 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// copyPartSize — размер части при копировании больших объектов.
+const copyPartSize = 512 << 20
+
+// Copy копирует объект внутри хранилища, не передавая содержимое через
+// сервис. Тип содержимого, метаданные и теги копируются; ACL копии —
+// acl или, если он пуст, ACL бакета.
+func (s *S3Store) Copy(ctx context.Context, src, dst ObjectRef, acl string) error {
+	info, err := s.Head(ctx, src.Bucket, src.Key)
+	if err != nil {
+		return err
+	}
+	if info.Size > MaxCopySize {
+		return s.multipartCopy(ctx, src, dst, info, acl)
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(dst.Bucket),
+		Key:               aws.String(dst.Key),
+		CopySource:        aws.String(copySource(src)),
+		CopySourceIfMatch: aws.String(info.ETag),
+	}
+	if acl != "" {
+		input.ACL = types.ObjectCannedACL(acl)
+	}
+	if _, err := s.client.CopyObject(ctx, input); err != nil {
+		return mapS3Error(err)
+	}
+	return nil
+}
+
+// multipartCopy копирует объект частями через UploadPartCopy. Части
+// копируются только пока у источника тот же ETag, поэтому изменение
+// источника во время копирования прерывает его.
+func (s *S3Store) multipartCopy(ctx context.Context, src, dst ObjectRef, info *ObjectInfo, acl string) error {
+	// В отличие от CopyObject, multipart-загрузка не переносит тип,
+	// метаданные и теги сама.
+	tags, err := s.Tags(ctx, src.Bucket, src.Key)
+	if err != nil {
+		return err
+	}
+	uploadID, err := s.CreateMultipartUpload(ctx, dst.Bucket, dst.Key, PutOptions{
+		ContentType: info.ContentType,
+		ACL:         acl,
+		Metadata:    info.Metadata,
+		Tags:        tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	partSize := int64(copyPartSize)
+	if n := (info.Size + partSize - 1) / partSize; n > MaxParts {
+		partSize = (info.Size + MaxParts - 1) / MaxParts
+	}
+	parts := make([]CompletedPart, (info.Size+partSize-1)/partSize)
+
+	concurrency := s.cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	copyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	// После ошибки части или отмены ctx новые части не запускаются.
+schedule:
+	for i := range parts {
+		start := int64(i) * partSize
+		end := min(start+partSize, info.Size) - 1
+		partNumber := i + 1
+
+		select {
+		case sem <- struct{}{}:
+		case <-copyCtx.Done():
+			break schedule
+		}
+		if copyCtx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+
+			output, err := s.client.UploadPartCopy(copyCtx, &s3.UploadPartCopyInput{
+				Bucket:            aws.String(dst.Bucket),
+				Key:               aws.String(dst.Key),
+				UploadId:          aws.String(uploadID),
+				PartNumber:        aws.Int32(int32(partNumber)),
+				CopySource:        aws.String(copySource(src)),
+				CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+				CopySourceIfMatch: aws.String(info.ETag),
+			})
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = mapS3Error(err)
+					cancel()
+				}
+				mu.Unlock()
+				return
+			}
+			etag := ""
+			if output.CopyPartResult != nil {
+				etag = aws.ToString(output.CopyPartResult.ETag)
+			}
+			parts[i] = CompletedPart{PartNumber: partNumber, ETag: etag}
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr == nil {
+		firstErr = s.CompleteMultipartUpload(ctx, dst.Bucket, dst.Key, uploadID, parts)
+	}
+	if firstErr != nil {
+		s.AbortMultipartUpload(context.WithoutCancel(ctx), dst.Bucket, dst.Key, uploadID)
+		return firstErr
+	}
+	return nil
+}
+
+// copySource кодирует источник копирования для заголовка
+// x-amz-copy-source: bucket/key с экранированием каждого сегмента.
+func copySource(src ObjectRef) string {
+	segments := strings.Split(src.Key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return src.Bucket + "/" + strings.Join(segments, "/")
+}
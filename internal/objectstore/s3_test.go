@@ -0,0 +1,106 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeDeleteServer отвечает на DeleteObjects и запоминает число ключей
+// в каждом запросе. Ключи из failKeys возвращаются с ошибкой.
+type fakeDeleteServer struct {
+	failKeys map[string]bool
+
+	mu     sync.Mutex
+	chunks []int
+}
+
+func (f *fakeDeleteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !r.URL.Query().Has("delete") || r.URL.Path != "/bucket" {
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.String(), http.StatusBadRequest)
+		return
+	}
+	var req struct {
+		Objects []struct {
+			Key string `xml:"Key"`
+		} `xml:"Object"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.chunks = append(f.chunks, len(req.Objects))
+	f.mu.Unlock()
+
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><DeleteResult>`)
+	for _, obj := range req.Objects {
+		if f.failKeys[obj.Key] {
+			fmt.Fprintf(w, `<Error><Key>%s</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`, obj.Key)
+		}
+	}
+	fmt.Fprint(w, `</DeleteResult>`)
+}
+
+func newTestS3(t *testing.T, handler http.Handler) *S3Store {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	store, err := NewS3(S3Config{
+		Endpoint:       srv.URL,
+		Region:         "us-east-1",
+		AccessKey:      "access",
+		SecretKey:      "secret",
+		ForcePathStyle: true,
+		HTTPClient:     srv.Client(),
+		Retry:          RetryConfig{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3DeleteManyChunks(t *testing.T) {
+	keys := make([]string, 2*MaxDeleteKeys+5)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%04d", i)
+	}
+	fake := &fakeDeleteServer{failKeys: map[string]bool{keys[3]: true, keys[MaxDeleteKeys+1]: true}}
+	store := newTestS3(t, fake)
+
+	failed, err := store.DeleteMany(context.Background(), "bucket", keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{MaxDeleteKeys, MaxDeleteKeys, 5}
+	if fmt.Sprint(fake.chunks) != fmt.Sprint(want) {
+		t.Errorf("chunks = %v, want %v", fake.chunks, want)
+	}
+	if len(failed) != 2 || failed[0].Key != keys[3] || failed[1].Key != keys[MaxDeleteKeys+1] {
+		t.Fatalf("failed = %+v", failed)
+	}
+	if failed[0].Code != "AccessDenied" || failed[0].Message != "Access Denied" {
+		t.Errorf("failed[0] = %+v", failed[0])
+	}
+}
+
+func TestS3DeleteManyEmpty(t *testing.T) {
+	fake := &fakeDeleteServer{}
+	store := newTestS3(t, fake)
+
+	failed, err := store.DeleteMany(context.Background(), "bucket", nil)
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("DeleteMany(nil) = %v, %v", failed, err)
+	}
+	if len(fake.chunks) != 0 {
+		t.Errorf("chunks = %v, want no requests", fake.chunks)
+	}
+}
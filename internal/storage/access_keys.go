@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"S3Storage/internal/clo"
+)
+
+// AccessKeys управляет ключами доступа S3 пользователя username в CLO:
+//
+//	POST   /access-keys       выпустить новую пару ключей
+//	GET    /access-keys       список ключей без секретов
+//	DELETE /access-keys?id=   удалить пару ключей
+//
+// Сервис работает с самой новой действующей парой, поэтому смена ключей —
+// это POST, а затем DELETE старой пары. По умолчанию username — сам
+// вызывающий.
+func AccessKeys(w http.ResponseWriter, r *http.Request) {
+	if !requireCLO(w) {
+		return
+	}
+
+	username := requestUser(r, r.URL.Query().Get("username"))
+	if !authorize(w, r, username) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		createAccessKey(w, r, username)
+	case http.MethodGet:
+		listAccessKeys(w, r, username)
+	case http.MethodDelete:
+		deleteAccessKey(w, r, username)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// accessKeyView — ключ доступа в ответах /access-keys; секретный ключ
+// показывается только при выпуске.
+type accessKeyView struct {
+	ID        string `json:"id"`
+	AccessKey string `json:"access_key"`
+	Status    string `json:"status"`
+	CreatedIn string `json:"created_in"`
+	InUse     bool   `json:"in_use"`
+}
+
+func createAccessKey(w http.ResponseWriter, r *http.Request, username string) {
+	userID, ok := cloUserID(w, r, username)
+	if !ok {
+		return
+	}
+
+	creds, err := cloClient.CreateCredentials(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Ошибка выпуска ключей: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	// Следующие запросы пойдут с новыми ключами.
+	InvalidateCredentials(username)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         creds.ID,
+		"access_key": creds.AccessKey,
+		"secret_key": creds.SecretKey,
+		"status":     creds.Status,
+		"created_in": creds.CreatedIn,
+	})
+}
+
+func listAccessKeys(w http.ResponseWriter, r *http.Request, username string) {
+	userID, ok := cloUserID(w, r, username)
+	if !ok {
+		return
+	}
+
+	creds, err := cloClient.ListCredentials(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Ошибка получения ключей: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	current, _ := newestCredentials(creds)
+
+	keys := make([]accessKeyView, 0, len(creds))
+	for _, c := range creds {
+		keys = append(keys, accessKeyView{
+			ID:        c.ID,
+			AccessKey: c.AccessKey,
+			Status:    c.Status,
+			CreatedIn: c.CreatedIn,
+			InUse:     c.AccessKey == current.AccessKey,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"access_keys": keys})
+}
+
+func deleteAccessKey(w http.ResponseWriter, r *http.Request, username string) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Отсутствует параметр id", http.StatusBadRequest)
+		return
+	}
+
+	userID, ok := cloUserID(w, r, username)
+	if !ok {
+		return
+	}
+
+	creds, err := cloClient.ListCredentials(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Ошибка получения ключей: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	found, active := false, 0
+	for _, c := range creds {
+		if c.ID == id {
+			found = true
+		} else if credentialsActive(c) {
+			active++
+		}
+	}
+	if !found {
+		http.Error(w, "Ключ не найден", http.StatusNotFound)
+		return
+	}
+	// Без действующих ключей сервис потеряет доступ к хранилищу
+	// пользователя.
+	if active == 0 {
+		http.Error(w, "Нельзя удалить последний действующий ключ: сначала выпустите новый", http.StatusConflict)
+		return
+	}
+
+	err = cloClient.DeleteCredentials(r.Context(), userID, id)
+	if clo.IsNotFound(err) {
+		http.Error(w, "Ключ не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка удаления ключа: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	InvalidateCredentials(username)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cloUserID возвращает идентификатор пользователя username в CLO и
+// отвечает 404 или 502, если получить его не удалось.
+func cloUserID(w http.ResponseWriter, r *http.Request, username string) (string, bool) {
+	userID, err := GetUserIdByName(r.Context(), username)
+	if clo.IsNotFound(err) {
+		http.Error(w, "Пользователь не найден", http.StatusNotFound)
+		return "", false
+	}
+	if err != nil {
+		http.Error(w, "Ошибка поиска пользователя: "+err.Error(), http.StatusBadGateway)
+		return "", false
+	}
+	return userID, true
+}
+
+// newestCredentials выбирает самую новую действующую пару ключей. Если
+// дату выпуска разобрать не удалось, новее считается пара, идущая в
+// списке позже.
+func newestCredentials(creds []clo.Credentials) (clo.Credentials, bool) {
+	var (
+		newest  clo.Credentials
+		created time.Time
+		found   bool
+	)
+	for _, c := range creds {
+		if !credentialsActive(c) {
+			continue
+		}
+		t, _ := time.Parse(time.RFC3339, c.CreatedIn)
+		if !found || !t.Before(created) {
+			newest, created, found = c, t, true
+		}
+	}
+	return newest, found
+}
+
+// credentialsActive сообщает, что пара ключей действует. Ключи без
+// статуса считаются действующими.
+func credentialsActive(c clo.Credentials) bool {
+	return c.Status == "" || c.Status == clo.CredentialsActive
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"S3Storage/internal/auth"
+	"S3Storage/internal/config"
+	"S3Storage/internal/objectstore"
+)
+
+// ACL загружаемых объектов, задаётся в Init.
+var (
+	defaultACL  = objectstore.ACLPrivate
+	allowedACLs = []string{objectstore.ACLPrivate, objectstore.ACLPublicRead}
+)
+
+func initACL(cfg config.ACL) error {
+	if len(cfg.Allowed) > 0 {
+		allowedACLs = cfg.Allowed
+	}
+	if cfg.Default != "" {
+		defaultACL = cfg.Default
+	}
+	if !aclAllowed(defaultACL) {
+		return fmt.Errorf("storage: default acl %q is not in storage.acl.allowed", defaultACL)
+	}
+	return nil
+}
+
+func aclAllowed(acl string) bool {
+	for _, a := range allowedACLs {
+		if a == acl {
+			return true
+		}
+	}
+	return false
+}
+
+// requestACL выбирает ACL по параметрам acl или visibility (public —
+// public-read, private — private). Без параметров — ACL по умолчанию.
+func requestACL(acl, visibility string) (string, error) {
+	switch {
+	case acl != "" && visibility != "":
+		return "", fmt.Errorf("нужно указать либо acl, либо visibility")
+	case visibility == "public":
+		acl = objectstore.ACLPublicRead
+	case visibility == "private":
+		acl = objectstore.ACLPrivate
+	case visibility != "":
+		return "", fmt.Errorf("visibility может быть public или private")
+	case acl == "":
+		return defaultACL, nil
+	}
+	if !aclAllowed(acl) {
+		return "", fmt.Errorf("ACL %s не разрешён, допустимые значения: %s", acl, strings.Join(allowedACLs, ", "))
+	}
+	return acl, nil
+}
+
+// FileACL показывает ACL объекта filename.
+func FileACL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, bucket, filename, ok := aclRequest(w, r)
+	if !ok {
+		return
+	}
+	if !authorizeObject(w, r, username, bucket, filename, auth.PermRead) {
+		return
+	}
+	writeFileACL(w, r, username, bucket, filename)
+}
+
+// SetFileACL меняет ACL объекта filename на acl (или visibility). Менять
+// видимость может только владелец или администратор.
+func SetFileACL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username, bucket, filename, ok := aclRequest(w, r)
+	if !ok {
+		return
+	}
+	if r.URL.Query().Get("acl") == "" && r.URL.Query().Get("visibility") == "" {
+		http.Error(w, "Отсутствует параметр acl", http.StatusBadRequest)
+		return
+	}
+	acl, err := requestACL(r.URL.Query().Get("acl"), r.URL.Query().Get("visibility"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !authorize(w, r, username) {
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	if err := store.SetACL(r.Context(), bucket, filename, acl); err != nil {
+		http.Error(w, "Ошибка изменения ACL: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	writeFileACL(w, r, username, bucket, filename)
+}
+
+// aclRequest проверяет параметры username, bucket и filename.
+func aclRequest(w http.ResponseWriter, r *http.Request) (string, string, string, bool) {
+	username := requestUser(r, r.URL.Query().Get("username"))
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		http.Error(w, "Отсутствует параметр filename", http.StatusBadRequest)
+		return "", "", "", false
+	}
+	bucket, ok := requestBucket(w, r, username, r.URL.Query().Get("bucket"))
+	if !ok {
+		return "", "", "", false
+	}
+	return username, bucket, filename, true
+}
+
+// writeFileACL отвечает текущим ACL объекта.
+func writeFileACL(w http.ResponseWriter, r *http.Request, username, bucket, filename string) {
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	acl, err := store.ACL(r.Context(), bucket, filename)
+	if err != nil {
+		http.Error(w, "Ошибка получения ACL: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":   filename,
+		"bucket": bucket,
+		"acl":    acl,
+		"public": acl == objectstore.ACLPublicRead || acl == objectstore.ACLPublicReadWrite,
+	})
+}
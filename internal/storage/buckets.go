@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"S3Storage/internal/auth"
+	"S3Storage/internal/objectstore"
+)
+
+// bucketOwned сообщает, принадлежит ли бакет пользователю username.
+// Бакет по умолчанию принадлежит ему всегда, остальные — если созданы им
+// через /create-bucket: для бэкендов s3 и local хранилище общее, и
+// владелец известен только из таблицы buckets.
+func bucketOwned(ctx context.Context, username, bucket string) (bool, error) {
+	if bucket == defaultBucket(username) {
+		return true, nil
+	}
+	var owner string
+	err := db.QueryRowContext(ctx, "SELECT owner FROM buckets WHERE name = $1", bucket).Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return owner == username, err
+}
+
+// ownedBuckets возвращает имена бакетов пользователя username, включая
+// бакет по умолчанию.
+func ownedBuckets(ctx context.Context, username string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM buckets WHERE owner = $1", username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := map[string]bool{defaultBucket(username): true}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[name] = true
+	}
+	return names, rows.Err()
+}
+
+// requestBucket возвращает бакет из параметра name или, если он пуст,
+// бакет по умолчанию и проверяет, что бакет принадлежит username.
+func requestBucket(w http.ResponseWriter, r *http.Request, username, name string) (string, bool) {
+	if name == "" {
+		return defaultBucket(username), true
+	}
+	if !objectstore.ValidBucketName(name) {
+		http.Error(w, "Некорректное имя бакета "+name, http.StatusBadRequest)
+		return "", false
+	}
+	owned, err := bucketOwned(r.Context(), username, name)
+	if err != nil {
+		http.Error(w, "Ошибка проверки бакета: "+err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	if !owned {
+		http.Error(w, "Бакет "+name+" не найден", http.StatusNotFound)
+		return "", false
+	}
+	return name, true
+}
+
+// CreateBucket создаёт бакет {"name"} пользователя username.
+func CreateBucket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := requestUser(r, r.URL.Query().Get("username"))
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !objectstore.ValidBucketName(req.Name) {
+		http.Error(w, "Некорректное имя бакета "+req.Name, http.StatusBadRequest)
+		return
+	}
+	// Бакеты по умолчанию принадлежат своим пользователям без записи в
+	// таблице, поэтому их имена занимать нельзя.
+	if strings.HasSuffix(req.Name, defaultBucketSuffix) && req.Name != defaultBucket(username) {
+		http.Error(w, "Имя "+req.Name+" зарезервировано", http.StatusConflict)
+		return
+	}
+
+	if !authorize(w, r, username) {
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	// Имя занимается в таблице до создания бакета, чтобы два
+	// пользователя не создали один бакет одновременно. Уже занятое имя —
+	// конфликт, даже если оно занято самим пользователем.
+	res, err := db.ExecContext(r.Context(), "INSERT INTO buckets (name, owner) VALUES ($1, $2) ON CONFLICT DO NOTHING", req.Name, username)
+	if err != nil {
+		http.Error(w, "Ошибка сохранения бакета: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		http.Error(w, "Бакет "+req.Name+" уже существует", http.StatusConflict)
+		return
+	}
+
+	// Запись остаётся, только если бакет создан: иначе через неё можно
+	// было бы получить доступ к чужому бакету, который уже есть в
+	// хранилище.
+	if err := store.CreateBucket(r.Context(), req.Name); err != nil {
+		db.ExecContext(context.WithoutCancel(r.Context()), "DELETE FROM buckets WHERE name = $1 AND owner = $2", req.Name, username)
+		http.Error(w, "Ошибка создания бакета: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"name": req.Name, "owner": username})
+}
+
+// ListBuckets возвращает бакеты пользователя username.
+func ListBuckets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := requestUser(r, r.URL.Query().Get("username"))
+	if !authorize(w, r, username) {
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	all, err := store.ListBuckets(r.Context())
+	if err != nil {
+		http.Error(w, "Ошибка получения бакетов: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	owned, err := ownedBuckets(r.Context(), username)
+	if err != nil {
+		http.Error(w, "Ошибка получения бакетов: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type BucketInfo struct {
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+		Default   bool      `json:"default"`
+	}
+	buckets := []BucketInfo{}
+	for _, b := range all {
+		if owned[b.Name] {
+			buckets = append(buckets, BucketInfo{
+				Name:      b.Name,
+				CreatedAt: b.CreatedAt,
+				Default:   b.Name == defaultBucket(username),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"buckets": buckets})
+}
+
+// DeleteBucket удаляет пустой бакет bucket пользователя username. Бакет
+// по умолчанию удалить нельзя.
+func DeleteBucket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := requestUser(r, r.URL.Query().Get("username"))
+	name := r.URL.Query().Get("bucket")
+	if name == "" {
+		http.Error(w, "Отсутствует параметр bucket", http.StatusBadRequest)
+		return
+	}
+	if name == defaultBucket(username) {
+		http.Error(w, "Бакет по умолчанию удалить нельзя", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, username) {
+		return
+	}
+	bucket, ok := requestBucket(w, r, username, name)
+	if !ok {
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	if err := store.DeleteBucket(r.Context(), bucket); err != nil && !errors.Is(err, objectstore.ErrBucketNotFound) {
+		http.Error(w, "Ошибка удаления бакета: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	if _, err := db.ExecContext(r.Context(), "DELETE FROM buckets WHERE name = $1", bucket); err != nil {
+		http.Error(w, "Ошибка удаления бакета: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := db.ExecContext(r.Context(), "DELETE FROM bucket_usage WHERE bucket = $1", bucket); err != nil {
+		http.Error(w, "Ошибка удаления бакета: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tokens.RevokeBucketGrants(r.Context(), username, bucket); err != nil {
+		http.Error(w, "Ошибка отзыва прав: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBucketInfo возвращает дату создания бакета, число объектов и их
+// общий размер.
+func GetBucketInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := requestUser(r, r.URL.Query().Get("username"))
+	bucket, ok := requestBucket(w, r, username, r.URL.Query().Get("bucket"))
+	if !ok {
+		return
+	}
+	if !authorizeObject(w, r, username, bucket, "", auth.PermRead) {
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	if err := store.HeadBucket(r.Context(), bucket); err != nil {
+		http.Error(w, "Ошибка получения бакета: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	all, err := store.ListBuckets(r.Context())
+	if err != nil {
+		http.Error(w, "Ошибка получения бакета: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	var createdAt time.Time
+	for _, b := range all {
+		if b.Name == bucket {
+			createdAt = b.CreatedAt
+		}
+	}
+
+	objects, err := objectstore.ListAll(r.Context(), store, bucket, objectstore.ListOptions{})
+	if err != nil {
+		http.Error(w, "Ошибка получения списка объектов: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	var size int64
+	for _, obj := range objects.Objects {
+		size += obj.Size
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":       bucket,
+		"owner":      username,
+		"created_at": createdAt,
+		"default":    bucket == defaultBucket(username),
+		"objects":    len(objects.Objects),
+		"size":       size,
+	})
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// sniffLen — сколько первых байт файла нужно http.DetectContentType.
+const sniffLen = 512
+
+// detectContentType определяет тип содержимого загружаемого файла:
+// по заявленному клиентом типу, затем по расширению имени, затем по
+// первым байтам head. Тип application/octet-stream браузеры ставят для
+// любого незнакомого файла, поэтому он не считается заявленным.
+func detectContentType(declared, filename string, head []byte) string {
+	if ct, ok := parseContentType(declared); ok && ct != "application/octet-stream" {
+		return ct
+	}
+	if ct, ok := parseContentType(mime.TypeByExtension(path.Ext(filename))); ok {
+		return ct
+	}
+	if len(head) > 0 {
+		return http.DetectContentType(head)
+	}
+	return "application/octet-stream"
+}
+
+// parseContentType проверяет синтаксис типа и приводит его к
+// каноническому виду.
+func parseContentType(ct string) (string, bool) {
+	if ct == "" {
+		return "", false
+	}
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", false
+	}
+	return mime.FormatMediaType(mediaType, params), true
+}
+
+// safeContentType возвращает тип для ответа со скачиванием: сохранённый
+// тип объекта или application/octet-stream, если его нет или он
+// некорректен.
+func safeContentType(ct string) string {
+	if ct, ok := parseContentType(ct); ok {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// inlineSafe сообщает, можно ли показать содержимое прямо в браузере.
+// HTML, SVG, XML и скрипты отдаются только вложением: иначе загруженный
+// файл выполнится в контексте сервиса.
+func inlineSafe(ct string) bool {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "video/"):
+		return true
+	}
+	switch mediaType {
+	case "text/plain", "text/csv", "application/pdf", "application/json":
+		return true
+	}
+	return false
+}
+
+// contentDisposition формирует Content-Disposition по RFC 6266: имя в
+// кодировке ASCII для старых клиентов и, если в нём есть другие
+// символы, filename* в UTF-8 по RFC 5987.
+func contentDisposition(dispositionType, filename string) string {
+	name := path.Base(filename)
+
+	var fallback strings.Builder
+	ascii := true
+	for _, c := range name {
+		switch {
+		case c == '"' || c == '\\' || c < ' ' || c == 0x7f:
+			fallback.WriteByte('_')
+		case c > 0x7e:
+			fallback.WriteByte('_')
+			ascii = false
+		default:
+			fallback.WriteRune(c)
+		}
+	}
+
+	header := dispositionType + `; filename="` + fallback.String() + `"`
+	if !ascii {
+		header += "; filename*=UTF-8''" + encodeRFC5987(name)
+	}
+	return header
+}
+
+// encodeRFC5987 кодирует s в процентной записи, оставляя как есть только
+// attr-char из RFC 5987.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"mime"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		declared string
+		filename string
+		head     []byte
+		want     string
+	}{
+		{"text/csv; charset=utf-8", "report.png", png, "text/csv; charset=utf-8"},
+		{"Text/CSV", "report", nil, "text/csv"},
+		// octet-stream и некорректный тип не считаются заявленными.
+		{"application/octet-stream", "photo.png", nil, "image/png"},
+		{"not a type", "doc.pdf", nil, "application/pdf"},
+		{"", "noext", png, "image/png"},
+		{"", "noext", []byte("<html><body>hi</body></html>"), "text/html; charset=utf-8"},
+		{"", "noext", nil, "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := detectContentType(tt.declared, tt.filename, tt.head); got != tt.want {
+			t.Errorf("detectContentType(%q, %q) = %q, want %q", tt.declared, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestSafeContentType(t *testing.T) {
+	tests := map[string]string{
+		"":                          "application/octet-stream",
+		"garbage;;":                 "application/octet-stream",
+		"image/PNG":                 "image/png",
+		"text/plain; charset=utf-8": "text/plain; charset=utf-8",
+	}
+	for ct, want := range tests {
+		if got := safeContentType(ct); got != want {
+			t.Errorf("safeContentType(%q) = %q, want %q", ct, got, want)
+		}
+	}
+}
+
+func TestInlineSafe(t *testing.T) {
+	tests := map[string]bool{
+		"image/png":                 true,
+		"video/mp4":                 true,
+		"application/pdf":           true,
+		"text/plain; charset=utf-8": true,
+		"image/svg+xml":             false,
+		"text/html":                 false,
+		"application/xhtml+xml":     false,
+		"text/javascript":           false,
+		"application/octet-stream":  false,
+		"":                          false,
+	}
+	for ct, want := range tests {
+		if got := inlineSafe(ct); got != want {
+			t.Errorf("inlineSafe(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		typ, filename, want string
+	}{
+		{"attachment", "report.pdf", `attachment; filename="report.pdf"`},
+		{"inline", "dir/sub/photo.png", `inline; filename="photo.png"`},
+		{"attachment", `a"b\c.txt`, `attachment; filename="a_b_c.txt"`},
+		{"attachment", "line\r\nbreak.txt", `attachment; filename="line__break.txt"`},
+		{"attachment", "отчёт 2024.pdf", `attachment; filename="_____ 2024.pdf"; filename*=UTF-8''%D0%BE%D1%82%D1%87%D1%91%D1%82%202024.pdf`},
+		{"attachment", "naïve;name.txt", `attachment; filename="na_ve;name.txt"; filename*=UTF-8''na%C3%AFve%3Bname.txt`},
+	}
+	for _, tt := range tests {
+		got := contentDisposition(tt.typ, tt.filename)
+		if got != tt.want {
+			t.Errorf("contentDisposition(%q, %q) = %s, want %s", tt.typ, tt.filename, got, tt.want)
+			continue
+		}
+		// Клиенты, понимающие filename*, должны получить исходное имя.
+		_, params, err := mime.ParseMediaType(got)
+		if err != nil {
+			t.Errorf("ParseMediaType(%s): %v", got, err)
+			continue
+		}
+		if strings.Contains(got, "filename*=") && params["filename"] != path.Base(tt.filename) {
+			t.Errorf("ParseMediaType(%s) filename = %q, want %q", got, params["filename"], path.Base(tt.filename))
+		}
+	}
+}
+
+func TestEncodeRFC5987(t *testing.T) {
+	tests := map[string]string{
+		"abc-XYZ_0.9": "abc-XYZ_0.9",
+		"a b":         "a%20b",
+		"100%":        "100%25",
+		"x'y*z":       "x%27y%2Az",
+		"!#$&+^`|~":   "!#$&+^`|~",
+		"файл":        "%D1%84%D0%B0%D0%B9%D0%BB",
+	}
+	for in, want := range tests {
+		if got := encodeRFC5987(in); got != want {
+			t.Errorf("encodeRFC5987(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
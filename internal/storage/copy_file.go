@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"S3Storage/internal/auth"
+	"S3Storage/internal/objectstore"
+)
+
+// transferRequest — тело запросов /copy-file, /move-file и /rename-file.
+// Пустые to_username и to_bucket означают того же пользователя и тот же
+// бакет, что у источника.
+type transferRequest struct {
+	Username   string `json:"username"`
+	Bucket     string `json:"bucket"`
+	Filename   string `json:"filename"`
+	ToUsername string `json:"to_username"`
+	ToBucket   string `json:"to_bucket"`
+	ToFilename string `json:"to_filename"`
+	ACL        string `json:"acl"`
+	Visibility string `json:"visibility"`
+	Overwrite  bool   `json:"overwrite"`
+}
+
+// CopyFile копирует файл на стороне хранилища, в том числе в другой
+// бакет или в бакет другого пользователя, к которому у вызывающего есть
+// право записи. ACL копии — acl/visibility или ACL по умолчанию.
+func CopyFile(w http.ResponseWriter, r *http.Request) {
+	transferFile(w, r, false, true)
+}
+
+// MoveFile переносит файл: копирует его, как CopyFile, и удаляет
+// исходный. ACL сохраняется, если не указан явно.
+func MoveFile(w http.ResponseWriter, r *http.Request) {
+	transferFile(w, r, true, true)
+}
+
+// RenameFile переименовывает файл внутри его бакета.
+func RenameFile(w http.ResponseWriter, r *http.Request) {
+	transferFile(w, r, true, false)
+}
+
+func transferFile(w http.ResponseWriter, r *http.Request, move, crossBucket bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req transferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Filename == "" || req.ToFilename == "" {
+		http.Error(w, "Нужно указать filename и to_filename", http.StatusBadRequest)
+		return
+	}
+	if !crossBucket && (req.ToUsername != "" || req.ToBucket != "") {
+		http.Error(w, "Переименование возможно только внутри бакета, для переноса используйте /move-file", http.StatusBadRequest)
+		return
+	}
+
+	// Перенос удаляет исходный файл, поэтому, кроме scope маршрута
+	// (storage:delete), нужен и storage:write.
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if move && !principal.Can(auth.ScopeWrite) {
+		http.Error(w, "Недостаточно прав: требуется "+auth.ScopeWrite, http.StatusForbidden)
+		return
+	}
+
+	srcOwner := requestUser(r, req.Username)
+	srcBucket, ok := requestBucket(w, r, srcOwner, req.Bucket)
+	if !ok {
+		return
+	}
+	dstOwner, dstBucketName := srcOwner, req.ToBucket
+	if req.ToUsername != "" {
+		dstOwner = req.ToUsername
+	}
+	if dstBucketName == "" && dstOwner == srcOwner {
+		dstBucketName = srcBucket
+	}
+	dstBucket, ok := requestBucket(w, r, dstOwner, dstBucketName)
+	if !ok {
+		return
+	}
+
+	src := objectstore.ObjectRef{Bucket: srcBucket, Key: req.Filename}
+	dst := objectstore.ObjectRef{Bucket: dstBucket, Key: req.ToFilename}
+	if srcOwner == dstOwner && src == dst {
+		http.Error(w, "Источник и назначение совпадают", http.StatusBadRequest)
+		return
+	}
+
+	if !authorizeObject(w, r, srcOwner, src.Bucket, src.Key, auth.PermRead) {
+		return
+	}
+	if move && !authorizeObject(w, r, srcOwner, src.Bucket, src.Key, auth.PermDelete) {
+		return
+	}
+	if !authorizeObject(w, r, dstOwner, dst.Bucket, dst.Key, auth.PermWrite) {
+		return
+	}
+
+	acl := ""
+	if req.ACL != "" || req.Visibility != "" || !move {
+		var err error
+		acl, err = requestACL(req.ACL, req.Visibility)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	srcStore, err := openStore(r.Context(), srcOwner)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	dstStore := srcStore
+	if dstOwner != srcOwner {
+		if dstStore, err = openStore(r.Context(), dstOwner); err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+	}
+
+	info, err := srcStore.Head(r.Context(), src.Bucket, src.Key)
+	if err != nil {
+		http.Error(w, "Ошибка получения исходного файла: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	replaced, err := existingObject(r.Context(), dstStore, dst.Bucket, dst.Key)
+	if err != nil {
+		http.Error(w, "Ошибка проверки файла назначения: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	if replaced != nil && !req.Overwrite {
+		http.Error(w, "Файл "+dst.Key+" уже существует, укажите overwrite", http.StatusConflict)
+		return
+	}
+
+	// Перенос внутри бакета не меняет заполнение, в остальных случаях
+	// копия должна поместиться в квоту назначения.
+	if !(move && srcOwner == dstOwner && src.Bucket == dst.Bucket) {
+		if _, err := checkQuota(r.Context(), dstStore, dstOwner, dst.Bucket, dst.Key, info.Size); err != nil {
+			http.Error(w, "Ошибка проверки квоты: "+err.Error(), quotaErrorStatus(err))
+			return
+		}
+	}
+
+	// При переносе без явного ACL копия получает ACL исходного файла.
+	if move && acl == "" {
+		if acl, err = srcStore.ACL(r.Context(), src.Bucket, src.Key); err != nil {
+			http.Error(w, "Ошибка получения ACL: "+err.Error(), storeErrorStatus(err))
+			return
+		}
+	}
+
+	if srcOwner == dstOwner {
+		err = srcStore.Copy(r.Context(), src, dst, acl)
+	} else {
+		err = copyBetweenStores(r.Context(), srcStore, dstStore, src, dst, acl)
+	}
+	if err != nil {
+		http.Error(w, "Ошибка копирования: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	recordWrite(r.Context(), dst.Bucket, replaced, info.Size)
+
+	result := map[string]interface{}{
+		"source":      map[string]string{"username": srcOwner, "bucket": src.Bucket, "filename": src.Key},
+		"destination": map[string]string{"username": dstOwner, "bucket": dst.Bucket, "filename": dst.Key},
+		"size":        info.Size,
+		"acl":         acl,
+		"copied":      true,
+		"deleted":     false,
+	}
+	status := http.StatusOK
+	if move {
+		// Копия уже создана: если исходный файл удалить не удалось, клиент
+		// узнаёт об этом из ответа и может повторить удаление сам.
+		if err := srcStore.Delete(r.Context(), src.Bucket, src.Key); err != nil {
+			status = storeErrorStatus(err)
+			result["error"] = "Файл скопирован, но исходный не удалён: " + err.Error()
+		} else {
+			adjustUsage(r.Context(), src.Bucket, -1, -info.Size)
+			result["deleted"] = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// copyBetweenStores копирует объект между хранилищами разных
+// пользователей. Их ключи доступа различаются, поэтому копирование на
+// стороне хранилища невозможно и содержимое проходит через сервис.
+func copyBetweenStores(ctx context.Context, srcStore, dstStore objectstore.Store, src, dst objectstore.ObjectRef, acl string) error {
+	obj, err := srcStore.Get(ctx, src.Bucket, src.Key, objectstore.GetOptions{})
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+
+	tags, err := srcStore.Tags(ctx, src.Bucket, src.Key)
+	if err != nil {
+		return err
+	}
+	return dstStore.Put(ctx, dst.Bucket, dst.Key, obj.Body, objectstore.PutOptions{
+		ContentType: obj.ContentType,
+		ACL:         acl,
+		Metadata:    obj.Metadata,
+		Tags:        tags,
+	})
+}
@@ -1,12 +1,12 @@
 package storage
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"strings"
+
+	"S3Storage/internal/auth"
+	"S3Storage/internal/clo"
 )
 
 func Create(w http.ResponseWriter, r *http.Request) {
@@ -15,8 +15,14 @@ func Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !requireCLO(w) {
+		return
+	}
+
 	var user struct {
 		Login string `json:"login"`
+		Role  string `json:"role"`
+		Plan  string `json:"plan"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&user)
@@ -30,84 +36,80 @@ func Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Извлечение токена из заголовка Authorization
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "Authorization header is required", http.StatusUnauthorized)
-		return
+	// Создавать пользователей может только администратор (scope admin
+	// проверяется при регистрации обработчика), поэтому запись в Person
+	// заводится здесь же, а не заранее.
+	role := auth.RoleOwner
+	if user.Role != "" {
+		if role, err = auth.ParseRole(user.Role); err != nil {
+			http.Error(w, "Неизвестная роль "+user.Role, http.StatusBadRequest)
+			return
+		}
 	}
 
-	// Проверка формата заголовка и извлечение токена
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+	planName, plan, ok := lookupPlan(user.Plan)
+	if !ok {
+		http.Error(w, "Неизвестный тариф "+user.Plan, http.StatusBadRequest)
 		return
 	}
-	token := parts[1]
 
-	authOk := CheckUser(user.Login, token)
-	if !authOk {
-		http.Error(w, "Failed to authentification", http.StatusInternalServerError)
-		return
-	}
-
-	projectID, err := GetProjectId()
+	projectID, err := GetProjectId(r.Context())
 	if err != nil {
-		fmt.Println(err)
+		http.Error(w, "Ошибка получения проекта: "+err.Error(), http.StatusBadGateway)
 		return
 	}
-	url := "https://api.clo.ru/v2/projects/" + projectID + "/s3/users"
-
-	// Define the JSON payload
-	payload := map[string]interface{}{
-		"bucket_quota": map[string]interface{}{
-			"max_objects": 10,
-			"max_size":    1000,
-		},
-		"canonical_name": user.Login,
-		"default_bucket": true,
-		"max_buckets":    10,
-		"name":           user.Login,
-		"user_quota": map[string]interface{}{
-			"max_objects": 10,
-			"max_size":    1000,
-		},
-	}
 
-	// Convert payload to JSON
-	jsonData, err := json.Marshal(payload)
+	created, err := cloClient.CreateUser(r.Context(), projectID, clo.CreateUserRequest{
+		Name:          user.Login,
+		CanonicalName: user.Login,
+		DefaultBucket: plan.DefaultBucket,
+		MaxBuckets:    plan.MaxBuckets,
+		BucketQuota:   quotaLimits(plan.BucketQuota),
+		UserQuota:     quotaLimits(plan.UserQuota),
+	})
 	if err != nil {
-		fmt.Println("Error marshalling JSON:", err)
+		http.Error(w, "Ошибка создания пользователя: "+err.Error(), http.StatusBadGateway)
 		return
 	}
 
-	// Create a new POST request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Println("Error creating request:", err)
+	// Без роли и тарифа пользователь неполон: созданный в CLO
+	// пользователь удаляется, чтобы запрос можно было повторить.
+	if err := tokens.SetRole(r.Context(), user.Login, role); err != nil {
+		http.Error(w, "Ошибка сохранения роли: "+err.Error()+discardCreatedUser(r.Context(), user.Login, created.ID), http.StatusInternalServerError)
 		return
 	}
-
-	// Set the headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request:", err)
+	if err := setUserPlan(r.Context(), user.Login, planName); err != nil {
+		http.Error(w, "Ошибка сохранения тарифа: "+err.Error()+discardCreatedUser(r.Context(), user.Login, created.ID), http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	respBody, err := ioutil.ReadAll(resp.Body)
+	// Первый токен выдаётся сразу, чтобы пользователь мог начать работу.
+	token, _, err := tokens.Issue(r.Context(), user.Login, "default", defaultTokenTTL, nil)
 	if err != nil {
-		fmt.Println("Error reading response body:", err)
+		http.Error(w, "Ошибка выпуска токена: "+err.Error()+discardCreatedUser(r.Context(), user.Login, created.ID), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]interface{}{"message": respBody})
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": created,
+		"role":    role,
+		"plan":    planName,
+		"token":   token,
+	})
+}
+
+// discardCreatedUser удаляет только что созданного пользователя CLO
+// userID и его запись в Person. Возвращает дополнение к сообщению об
+// ошибке, если удалить его не удалось.
+func discardCreatedUser(ctx context.Context, login, userID string) string {
+	ctx = context.WithoutCancel(ctx)
+	if err := cloClient.DeleteUser(ctx, userID); err != nil {
+		return "; пользователь " + login + " остался в CLO: " + err.Error()
+	}
+	if err := tokens.DeleteUser(ctx, login); err != nil {
+		return "; не удалось удалить запись пользователя: " + err.Error()
+	}
+	return ""
 }
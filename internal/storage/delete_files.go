@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"S3Storage/internal/auth"
+	"S3Storage/internal/objectstore"
+)
+
+// maxBulkDeleteKeys ограничивает список keys в одном запросе /delete-files.
+const maxBulkDeleteKeys = 10 * objectstore.MaxDeleteKeys
+
+// Результаты удаления отдельного ключа в ответе /delete-files.
+const (
+	deleteStatusDeleted     = "deleted"
+	deleteStatusWouldDelete = "would_delete"
+	deleteStatusNotFound    = "not_found"
+	deleteStatusForbidden   = "forbidden"
+	deleteStatusFailed      = "failed"
+)
+
+type deleteResult struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DeleteFiles удаляет несколько файлов одним запросом. Тело:
+//
+//	{"username", "bucket", "keys": [...], "prefix": "...", "dry_run": true}
+//
+// Указываются либо keys, либо непустой prefix — тогда удаляются все
+// файлы с этим префиксом. С dry_run ничего не удаляется, а в ответе
+// перечислены файлы, которые были бы удалены. Результат возвращается
+// для каждого ключа.
+func DeleteFiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Username string   `json:"username"`
+		Bucket   string   `json:"bucket"`
+		Keys     []string `json:"keys"`
+		Prefix   string   `json:"prefix"`
+		DryRun   bool     `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if (len(req.Keys) == 0) == (req.Prefix == "") {
+		http.Error(w, "Нужно указать либо keys, либо непустой prefix", http.StatusBadRequest)
+		return
+	}
+	if len(req.Keys) > maxBulkDeleteKeys {
+		http.Error(w, fmt.Sprintf("В keys может быть не больше %d ключей", maxBulkDeleteKeys), http.StatusBadRequest)
+		return
+	}
+
+	username := requestUser(r, req.Username)
+	bucket, ok := requestBucket(w, r, username, req.Bucket)
+	if !ok {
+		return
+	}
+	// Право на префикс покрывает все ключи под ним; ключи из списка
+	// проверяются по одному ниже.
+	if req.Prefix != "" && !authorizeObject(w, r, username, bucket, req.Prefix, auth.PermDelete) {
+		return
+	}
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	var results []deleteResult
+	var keys []string
+	if req.Prefix != "" {
+		list, err := objectstore.ListAll(r.Context(), store, bucket, objectstore.ListOptions{Prefix: req.Prefix})
+		if err != nil {
+			http.Error(w, "Ошибка получения списка объектов: "+err.Error(), storeErrorStatus(err))
+			return
+		}
+		for _, obj := range list.Objects {
+			keys = append(keys, obj.Key)
+		}
+	} else {
+		seen := make(map[string]bool, len(req.Keys))
+		for _, key := range req.Keys {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			allowed, err := objectAllowed(r.Context(), principal, username, bucket, key, auth.PermDelete)
+			if err != nil {
+				http.Error(w, "Ошибка проверки прав: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				results = append(results, deleteResult{Key: key, Status: deleteStatusForbidden})
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if req.DryRun {
+		results = append(results, dryRunResults(r, store, bucket, keys, req.Prefix != "")...)
+		writeDeleteResults(w, true, results)
+		return
+	}
+
+	results = append(results, deleteKeys(r.Context(), store, bucket, keys)...)
+	// Размеры удалённых ключей из списка неизвестны, поэтому бакет
+	// пересчитывается при следующей проверке квоты.
+	invalidateUsage(r.Context(), bucket)
+	writeDeleteResults(w, false, results)
+}
+
+// deleteKeys удаляет keys частями по objectstore.MaxDeleteKeys. Части
+// удаляются по очереди, чтобы при отказе хранилища сообщить, какие
+// ключи уже удалены, а какие нет.
+func deleteKeys(ctx context.Context, store objectstore.Store, bucket string, keys []string) []deleteResult {
+	results := make([]deleteResult, 0, len(keys))
+	for start := 0; start < len(keys); start += objectstore.MaxDeleteKeys {
+		chunk := keys[start:min(start+objectstore.MaxDeleteKeys, len(keys))]
+		failed, err := store.DeleteMany(ctx, bucket, chunk)
+		if err != nil {
+			for _, key := range keys[start:] {
+				results = append(results, deleteResult{Key: key, Status: deleteStatusFailed, Error: err.Error()})
+			}
+			break
+		}
+
+		errs := make(map[string]objectstore.DeleteError, len(failed))
+		for _, e := range failed {
+			errs[e.Key] = e
+		}
+		for _, key := range chunk {
+			if e, ok := errs[key]; ok {
+				results = append(results, deleteResult{Key: key, Status: deleteStatusFailed, Error: e.Code + ": " + e.Message})
+			} else {
+				results = append(results, deleteResult{Key: key, Status: deleteStatusDeleted})
+			}
+		}
+	}
+	return results
+}
+
+// dryRunResults сообщает, какие ключи были бы удалены. Ключи из списка
+// префикса уже существуют, остальные проверяются через Head.
+func dryRunResults(r *http.Request, store objectstore.Store, bucket string, keys []string, listed bool) []deleteResult {
+	results := make([]deleteResult, 0, len(keys))
+	for _, key := range keys {
+		if listed {
+			results = append(results, deleteResult{Key: key, Status: deleteStatusWouldDelete})
+			continue
+		}
+		_, err := store.Head(r.Context(), bucket, key)
+		switch {
+		case err == nil:
+			results = append(results, deleteResult{Key: key, Status: deleteStatusWouldDelete})
+		case errors.Is(err, objectstore.ErrNotFound):
+			results = append(results, deleteResult{Key: key, Status: deleteStatusNotFound})
+		default:
+			results = append(results, deleteResult{Key: key, Status: deleteStatusFailed, Error: err.Error()})
+		}
+	}
+	return results
+}
+
+func writeDeleteResults(w http.ResponseWriter, dryRun bool, results []deleteResult) {
+	counts := map[string]int{}
+	for _, res := range results {
+		counts[res.Status]++
+	}
+	if results == nil {
+		results = []deleteResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dry_run": dryRun,
+		"counts":  counts,
+		"results": results,
+	})
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"S3Storage/internal/objectstore"
+)
+
+// deleteManyStore записывает вызовы DeleteMany. Остальные методы
+// objectstore.Store в тестах не вызываются.
+type deleteManyStore struct {
+	objectstore.Store
+
+	calls  [][]string
+	failAt int // номер вызова, который вернёт ошибку; 0 — без ошибок
+	failed map[string]bool
+}
+
+func (s *deleteManyStore) DeleteMany(ctx context.Context, bucket string, keys []string) ([]objectstore.DeleteError, error) {
+	s.calls = append(s.calls, keys)
+	if len(s.calls) == s.failAt {
+		return nil, errors.New("storage unavailable")
+	}
+	var failed []objectstore.DeleteError
+	for _, key := range keys {
+		if s.failed[key] {
+			failed = append(failed, objectstore.DeleteError{Key: key, Code: "AccessDenied", Message: "denied"})
+		}
+	}
+	return failed, nil
+}
+
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%05d", i)
+	}
+	return keys
+}
+
+func TestDeleteKeysChunks(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []int
+	}{
+		{0, nil},
+		{1, []int{1}},
+		{objectstore.MaxDeleteKeys, []int{objectstore.MaxDeleteKeys}},
+		{objectstore.MaxDeleteKeys + 1, []int{objectstore.MaxDeleteKeys, 1}},
+		{maxBulkDeleteKeys, nil},
+	}
+	for _, tt := range tests {
+		if tt.n == maxBulkDeleteKeys {
+			for i := 0; i < maxBulkDeleteKeys/objectstore.MaxDeleteKeys; i++ {
+				tt.want = append(tt.want, objectstore.MaxDeleteKeys)
+			}
+		}
+		store := &deleteManyStore{}
+		keys := testKeys(tt.n)
+
+		results := deleteKeys(context.Background(), store, "bucket", keys)
+
+		var sizes []int
+		for _, call := range store.calls {
+			sizes = append(sizes, len(call))
+		}
+		if fmt.Sprint(sizes) != fmt.Sprint(tt.want) {
+			t.Errorf("%d keys: chunks = %v, want %v", tt.n, sizes, tt.want)
+		}
+		if len(results) != tt.n {
+			t.Fatalf("%d keys: %d results", tt.n, len(results))
+		}
+		for i, res := range results {
+			if res.Key != keys[i] || res.Status != deleteStatusDeleted {
+				t.Fatalf("%d keys: results[%d] = %+v", tt.n, i, res)
+			}
+		}
+	}
+}
+
+func TestDeleteKeysFailures(t *testing.T) {
+	keys := testKeys(3*objectstore.MaxDeleteKeys - 10)
+	store := &deleteManyStore{
+		failAt: 2,
+		failed: map[string]bool{keys[5]: true},
+	}
+
+	results := deleteKeys(context.Background(), store, "bucket", keys)
+
+	if len(store.calls) != 2 {
+		t.Errorf("DeleteMany called %d times, want 2: remaining chunks are skipped after an error", len(store.calls))
+	}
+	if len(results) != len(keys) {
+		t.Fatalf("%d results, want %d", len(results), len(keys))
+	}
+	for i, res := range results {
+		want := deleteStatusDeleted
+		switch {
+		case i == 5:
+			want = deleteStatusFailed
+			if res.Error != "AccessDenied: denied" {
+				t.Errorf("results[5].Error = %q", res.Error)
+			}
+		case i >= objectstore.MaxDeleteKeys:
+			want = deleteStatusFailed
+			if res.Error != "storage unavailable" {
+				t.Errorf("results[%d].Error = %q", i, res.Error)
+			}
+		}
+		if res.Key != keys[i] || res.Status != want {
+			t.Fatalf("results[%d] = %+v, want status %s", i, res, want)
+		}
+	}
+}
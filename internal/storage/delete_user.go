@@ -2,10 +2,9 @@ package storage
 
 import (
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"strings"
+
+	"S3Storage/internal/clo"
 )
 
 func Delete(w http.ResponseWriter, r *http.Request) {
@@ -14,6 +13,10 @@ func Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !requireCLO(w) {
+		return
+	}
+
 	var user struct {
 		Login string `json:"login"`
 	}
@@ -29,59 +32,31 @@ func Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Извлечение токена из заголовка Authorization
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "Authorization header is required", http.StatusUnauthorized)
-		return
-	}
-
-	// Проверка формата заголовка и извлечение токена
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	token := parts[1]
-
-	authOk := CheckUser(user.Login, token)
-	if !authOk {
-		http.Error(w, "Failed to authentification", http.StatusInternalServerError)
-	}
-
-	userID, err := GetUserIdByName(user.Login)
-	if err != nil {
-		fmt.Println(err)
-	}
-	url := "https://api.clo.ru/v2/s3/users/" + userID
-
-	// Create a new DELETE request
-	req, err := http.NewRequest("DELETE", url, nil)
+	userID, err := GetUserIdByName(r.Context(), user.Login)
 	if err != nil {
-		fmt.Println("Error creating request:", err)
+		if clo.IsNotFound(err) {
+			http.Error(w, "Пользователь не найден", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Ошибка поиска пользователя: "+err.Error(), http.StatusBadGateway)
 		return
 	}
 
-	// Set the headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error sending request:", err)
+	if err := cloClient.DeleteUser(r.Context(), userID); err != nil {
+		http.Error(w, "Ошибка удаления пользователя: "+err.Error(), http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
+	InvalidateCredentials(user.Login)
+
+	// Токены, роль, бакеты, ссылки и загрузки удалённого пользователя
+	// больше не действуют.
+	if err := tokens.DeleteUser(r.Context(), user.Login); err != nil {
+		http.Error(w, "Ошибка удаления данных пользователя: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]interface{}{"message": body})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Пользователь " + user.Login + " удалён"})
 }
@@ -1,90 +1,151 @@
 package storage
 
 import (
-	"fmt"
+	"errors"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"S3Storage/internal/auth"
+	"S3Storage/internal/objectstore"
 )
 
 func DownloadFileFromS3(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		return
 	}
 
-	username := r.URL.Query().Get("username")
+	username := requestUser(r, r.URL.Query().Get("username"))
 	filename := r.URL.Query().Get("filename")
-	if username == "" || filename == "" {
-		http.Error(w, "Отсутствуют параметры username или filename", http.StatusBadRequest)
+	if filename == "" {
+		http.Error(w, "Отсутствует параметр filename", http.StatusBadRequest)
 		return
 	}
-	bucketName := username + "-default-bucket"
-
-	// Извлечение токена из заголовка Authorization
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "Authorization header is required", http.StatusUnauthorized)
-		return
-	}
-
-	// Проверка формата заголовка и извлечение токена
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+	bucketName, ok := requestBucket(w, r, username, r.URL.Query().Get("bucket"))
+	if !ok {
 		return
 	}
-	token := parts[1]
 
-	authOk := CheckUser(username, token)
-	if !authOk {
-		http.Error(w, "Failed to authentification", http.StatusInternalServerError)
+	if !authorizeObject(w, r, username, bucketName, filename, auth.PermRead) {
 		return
 	}
 
-	accessKey, secretKey := GetKeys(username)
-	if accessKey == "" || secretKey == "" {
-		fmt.Errorf("error getting keys")
-		return
-	}
-	sess, err := session.NewSession(&aws.Config{
-		Region:           aws.String("us-west-2"),
-		Credentials:      credentials.NewStaticCredentials(accessKey, secretKey, ""),
-		Endpoint:         aws.String("https://storage.clo.ru"),
-		S3ForcePathStyle: aws.Bool(true),
-	})
+	store, err := openStore(r.Context(), username)
 	if err != nil {
-		fmt.Errorf("failed to create session, %v", err)
+		http.Error(w, err.Error(), storeErrorStatus(err))
 		return
 	}
 
-	// Create S3 service client
-	svc := s3.New(sess)
+	serveObject(w, r, store, bucketName, filename)
+}
 
-	// Get the file from S3
-	output, err := svc.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(filename),
-	})
-	if err != nil {
-		fmt.Errorf("failed to get object, %v", err)
-		return
+// serveObject отдаёт объект filename из бакета bucketName с учётом Range
+// и условных заголовков и возвращает код ответа.
+func serveObject(w http.ResponseWriter, r *http.Request, store objectstore.Store, bucketName, filename string) int {
+	// If-Range: диапазон действует, только если объект не изменился,
+	// иначе клиент получает файл целиком.
+	opts := getOptions(r)
+	if ifRange := r.Header.Get("If-Range"); ifRange != "" && opts.Range != "" {
+		info, err := store.Head(r.Context(), bucketName, filename)
+		if err == nil && !ifRangeMatches(ifRange, info) {
+			opts.Range = ""
+		}
+	}
+
+	// Получение файла из хранилища с учётом Range и условных заголовков
+	output, err := store.Get(r.Context(), bucketName, filename, opts)
+	switch {
+	case errors.Is(err, objectstore.ErrNotModified):
+		// Ответ 304 должен содержать те же ETag и Last-Modified, что и
+		// полный ответ, чтобы клиент обновил свою копию в кэше.
+		if info, err := store.Head(r.Context(), bucketName, filename); err == nil {
+			setValidators(w, info.ETag, info.LastModified)
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return http.StatusNotModified
+	case errors.Is(err, objectstore.ErrPreconditionFailed):
+		http.Error(w, "Условие запроса не выполнено", http.StatusPreconditionFailed)
+		return http.StatusPreconditionFailed
+	case errors.Is(err, objectstore.ErrInvalidRange):
+		http.Error(w, "Запрошенный диапазон недоступен", http.StatusRequestedRangeNotSatisfiable)
+		return http.StatusRequestedRangeNotSatisfiable
+	case err != nil:
+		http.Error(w, "failed to get object: "+err.Error(), storeErrorStatus(err))
+		return storeErrorStatus(err)
 	}
 	defer output.Body.Close()
 
+	// disposition=inline показывает файл в браузере, если его тип это
+	// допускает; по умолчанию файл скачивается.
+	contentType := safeContentType(output.ContentType)
+	disposition := "attachment"
+	if r.URL.Query().Get("disposition") == "inline" && inlineSafe(contentType) {
+		disposition = "inline"
+	}
+
 	// Установка заголовков для ответа
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	w.Header().Set("Content-Type", *output.ContentType)
+	w.Header().Set("Content-Disposition", contentDisposition(disposition, filename))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(output.ContentLength, 10))
+	setValidators(w, output.ETag, output.LastModified)
 
-	// Копирование содержимого файла в http.ResponseWriter
-	_, err = io.Copy(w, output.Body)
-	if err != nil {
-		http.Error(w, "Ошибка при отправке файла: "+err.Error(), http.StatusInternalServerError)
-		return
+	status := http.StatusOK
+	if output.ContentRange != "" {
+		w.Header().Set("Content-Range", output.ContentRange)
+		status = http.StatusPartialContent
+	}
+	w.WriteHeader(status)
+
+	if r.Method == http.MethodHead {
+		return status
+	}
+
+	// Копирование содержимого файла в http.ResponseWriter. Заголовки уже
+	// отправлены, поэтому при ошибке соединение просто обрывается.
+	if _, err := io.Copy(w, output.Body); err != nil {
+		log.Println("download:", err)
+	}
+	return status
+}
+
+// setValidators устанавливает заголовки ETag и Last-Modified.
+func setValidators(w http.ResponseWriter, etag string, modified time.Time) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// getOptions переносит заголовки Range и условного запроса в параметры
+// чтения объекта.
+func getOptions(r *http.Request) objectstore.GetOptions {
+	opts := objectstore.GetOptions{
+		Range:       r.Header.Get("Range"),
+		IfMatch:     r.Header.Get("If-Match"),
+		IfNoneMatch: r.Header.Get("If-None-Match"),
+	}
+	if t, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		opts.IfModifiedSince = t
+	}
+	if t, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil {
+		opts.IfUnmodifiedSince = t
+	}
+	return opts
+}
+
+// ifRangeMatches проверяет значение If-Range: дату последнего изменения
+// или ETag (только сильное сравнение).
+func ifRangeMatches(ifRange string, info *objectstore.ObjectInfo) bool {
+	if t, err := http.ParseTime(ifRange); err == nil {
+		return info.LastModified.Truncate(time.Second).Equal(t)
 	}
+	return !strings.HasPrefix(ifRange, "W/") && ifRange == info.ETag
 }
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"S3Storage/internal/objectstore"
+)
+
+func newTestLocalStore(t *testing.T) objectstore.Store {
+	t.Helper()
+	store, err := objectstore.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := store.CreateBucket(ctx, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+	err = store.Put(ctx, "bucket", "hello.txt", strings.NewReader("hello, world"), objectstore.PutOptions{ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestServeObjectNotModified(t *testing.T) {
+	store := newTestLocalStore(t)
+
+	w := httptest.NewRecorder()
+	serveObject(w, httptest.NewRequest(http.MethodGet, "/download-file", nil), store, "bucket", "hello.txt")
+	if w.Code != http.StatusOK || w.Body.String() != "hello, world" {
+		t.Fatalf("GET = %d %q", w.Code, w.Body.String())
+	}
+	etag, modified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q", etag, modified)
+	}
+
+	for _, header := range []string{"If-None-Match", "If-Modified-Since"} {
+		value := etag
+		if header == "If-Modified-Since" {
+			value = modified
+		}
+		r := httptest.NewRequest(http.MethodGet, "/download-file", nil)
+		r.Header.Set(header, value)
+		w := httptest.NewRecorder()
+
+		if status := serveObject(w, r, store, "bucket", "hello.txt"); status != http.StatusNotModified || w.Code != http.StatusNotModified {
+			t.Fatalf("%s: status = %d, code = %d, want 304", header, status, w.Code)
+		}
+		if got := w.Header().Get("ETag"); got != etag {
+			t.Errorf("%s: 304 ETag = %q, want %q", header, got, etag)
+		}
+		if got := w.Header().Get("Last-Modified"); got != modified {
+			t.Errorf("%s: 304 Last-Modified = %q, want %q", header, got, modified)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("%s: 304 body = %q", header, w.Body.String())
+		}
+	}
+}
+
+func TestServeObjectRange(t *testing.T) {
+	store := newTestLocalStore(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/download-file", nil)
+	r.Header.Set("Range", "bytes=7-")
+	w := httptest.NewRecorder()
+	serveObject(w, r, store, "bucket", "hello.txt")
+
+	if w.Code != http.StatusPartialContent || w.Body.String() != "world" {
+		t.Fatalf("Range GET = %d %q", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Range"); got != "bytes 7-11/12" {
+		t.Errorf("Content-Range = %q", got)
+	}
+
+	// If-Range с другим ETag отменяет диапазон.
+	r.Header.Set("If-Range", `"other"`)
+	w = httptest.NewRecorder()
+	serveObject(w, r, store, "bucket", "hello.txt")
+	if w.Code != http.StatusOK || w.Body.String() != "hello, world" {
+		t.Fatalf("If-Range GET = %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"S3Storage/internal/auth"
+)
+
+// FileInfo отдаёт сведения об объекте filename без его содержимого:
+// размер, тип, ETag, класс хранения, пользовательские метаданные и теги.
+func FileInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := requestUser(r, r.URL.Query().Get("username"))
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		http.Error(w, "Отсутствует параметр filename", http.StatusBadRequest)
+		return
+	}
+	bucketName, ok := requestBucket(w, r, username, r.URL.Query().Get("bucket"))
+	if !ok {
+		return
+	}
+
+	if !authorizeObject(w, r, username, bucketName, filename, auth.PermRead) {
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	info, err := store.Head(r.Context(), bucketName, filename)
+	if err != nil {
+		http.Error(w, "Ошибка получения сведений об объекте: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	tags, err := store.Tags(r.Context(), bucketName, filename)
+	if err != nil {
+		http.Error(w, "Ошибка получения тегов: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	// Класс хранения не указывается для объектов в стандартном классе.
+	storageClass := info.StorageClass
+	if storageClass == "" {
+		storageClass = "STANDARD"
+	}
+	metadata := info.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":          info.Key,
+		"bucket":        bucketName,
+		"size":          info.Size,
+		"last_modified": info.LastModified.Format("2006-01-02 15:04:05"),
+		"content_type":  info.ContentType,
+		"etag":          info.ETag,
+		"storage_class": storageClass,
+		"metadata":      metadata,
+		"tags":          tags,
+	})
+}
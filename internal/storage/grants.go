@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"S3Storage/internal/auth"
+)
+
+// Grants управляет правами доступа других пользователей к бакету
+// пользователя username:
+//
+//	POST   /grants        выдать право {"grantee", "permission", "bucket", "prefix"}
+//	GET    /grants        выданные и полученные права
+//	DELETE /grants?id=    отозвать право
+//
+// По умолчанию username — сам вызывающий.
+func Grants(w http.ResponseWriter, r *http.Request) {
+	username := requestUser(r, r.URL.Query().Get("username"))
+
+	switch r.Method {
+	case http.MethodPost:
+		createGrant(w, r, username)
+	case http.MethodGet:
+		listGrants(w, r, username)
+	case http.MethodDelete:
+		revokeGrant(w, r, username)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+func createGrant(w http.ResponseWriter, r *http.Request, username string) {
+	var req struct {
+		Grantee    string `json:"grantee"`
+		Permission string `json:"permission"`
+		Bucket     string `json:"bucket"`
+		Prefix     string `json:"prefix"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Grantee == "" || req.Grantee == username {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	perm, err := auth.ParsePermission(req.Permission)
+	if err != nil {
+		http.Error(w, "Неизвестное право "+req.Permission, http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, username) {
+		return
+	}
+	bucket, ok := requestBucket(w, r, username, req.Bucket)
+	if !ok {
+		return
+	}
+
+	grant := &auth.Grant{
+		Owner:      username,
+		Bucket:     bucket,
+		Prefix:     req.Prefix,
+		Grantee:    req.Grantee,
+		Permission: perm,
+	}
+	err = tokens.CreateGrant(r.Context(), grant)
+	if errors.Is(err, auth.ErrGrantExists) {
+		http.Error(w, "Такое право уже выдано", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка выдачи права: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(grant)
+}
+
+func listGrants(w http.ResponseWriter, r *http.Request, username string) {
+	if !authorize(w, r, username) {
+		return
+	}
+
+	granted, err := tokens.Grants(r.Context(), username)
+	if err != nil {
+		http.Error(w, "Ошибка получения прав: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	received, err := tokens.ReceivedGrants(r.Context(), username)
+	if err != nil {
+		http.Error(w, "Ошибка получения прав: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"granted":  granted,
+		"received": received,
+	})
+}
+
+func revokeGrant(w http.ResponseWriter, r *http.Request, username string) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Отсутствует параметр id", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, username) {
+		return
+	}
+
+	err := tokens.RevokeGrant(r.Context(), username, id)
+	if errors.Is(err, auth.ErrGrantNotFound) {
+		http.Error(w, "Право не найдено", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка отзыва права: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
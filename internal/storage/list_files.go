@@ -4,12 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
+	"strconv"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"S3Storage/internal/auth"
+	"S3Storage/internal/objectstore"
 )
 
 func ListFilesInBucket(w http.ResponseWriter, r *http.Request) {
@@ -18,63 +16,48 @@ func ListFilesInBucket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.URL.Query().Get("username")
-	if username == "" {
-		http.Error(w, "Отсутствуют параметры username или filename", http.StatusBadRequest)
+	username := requestUser(r, r.URL.Query().Get("username"))
+	bucketName, ok := requestBucket(w, r, username, r.URL.Query().Get("bucket"))
+	if !ok {
 		return
 	}
-	bucketName := username + "-default-bucket"
 
-	// Извлечение токена из заголовка Authorization
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "Authorization header is required", http.StatusUnauthorized)
+	if !authorizeObject(w, r, username, bucketName, r.URL.Query().Get("prefix"), auth.PermRead) {
 		return
 	}
 
-	// Проверка формата заголовка и извлечение токена
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
-		return
-	}
-	token := parts[1]
-
-	authOk := CheckUser(username, token)
-	if !authOk {
-		http.Error(w, "Failed to authentification", http.StatusInternalServerError)
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
 		return
 	}
 
-	accessKey, secretKey := GetKeys(username)
-	if accessKey == "" || secretKey == "" {
-		fmt.Errorf("error getting keys")
-		return
+	// Параметры страницы: prefix, delimiter, page_size, continuation_token.
+	// При all=true обходятся все страницы и возвращается полный список.
+	query := r.URL.Query()
+	opts := objectstore.ListOptions{
+		Prefix:            query.Get("prefix"),
+		Delimiter:         query.Get("delimiter"),
+		ContinuationToken: query.Get("continuation_token"),
 	}
-
-	sess, err := session.NewSession(&aws.Config{
-		Region:           aws.String("us-west-2"),
-		Credentials:      credentials.NewStaticCredentials(accessKey, secretKey, ""),
-		Endpoint:         aws.String("https://storage.clo.ru"),
-		S3ForcePathStyle: aws.Bool(true),
-	})
-	if err != nil {
-		fmt.Errorf("failed to create session, %v", err)
-		return
+	if v := query.Get("page_size"); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil || pageSize <= 0 || pageSize > objectstore.MaxListKeys {
+			http.Error(w, fmt.Sprintf("page_size должен быть от 1 до %d", objectstore.MaxListKeys), http.StatusBadRequest)
+			return
+		}
+		opts.MaxKeys = pageSize
 	}
 
-	// Создание клиента S3
-	svc := s3.New(sess)
-
-	// Параметры для ListObjectsV2
-	params := &s3.ListObjectsV2Input{
-		Bucket: aws.String(bucketName),
+	// Получение списка объектов
+	var result *objectstore.ListResult
+	if query.Get("all") == "true" {
+		result, err = objectstore.ListAll(r.Context(), store, bucketName, opts)
+	} else {
+		result, err = store.List(r.Context(), bucketName, opts)
 	}
-
-	// Вызов ListObjectsV2 для получения списка объектов
-	resp, err := svc.ListObjectsV2(params)
 	if err != nil {
-		fmt.Errorf("ошибка при получении списка объектов: %w", err)
+		http.Error(w, "ошибка при получении списка объектов: "+err.Error(), storeErrorStatus(err))
 		return
 	}
 
@@ -86,25 +69,30 @@ func ListFilesInBucket(w http.ResponseWriter, r *http.Request) {
 
 	// Response представляет JSON ответ
 	type Response struct {
-		Files []FileInfo `json:"files"`
+		Files                 []FileInfo `json:"files"`
+		Folders               []string   `json:"folders"`
+		IsTruncated           bool       `json:"is_truncated"`
+		NextContinuationToken string     `json:"next_continuation_token,omitempty"`
 	}
 
-	var files []FileInfo
-	for _, item := range resp.Contents {
+	files := []FileInfo{}
+	for _, item := range result.Objects {
 		files = append(files, FileInfo{
-			Name:         *item.Key,
-			Size:         *item.Size,
+			Name:         item.Key,
+			Size:         item.Size,
 			LastModified: item.LastModified.Format("2006-01-02 15:04:05"),
 		})
 	}
 
 	response := Response{
-		Files: files,
+		Files:                 files,
+		Folders:               append([]string{}, result.CommonPrefixes...),
+		IsTruncated:           result.IsTruncated,
+		NextContinuationToken: result.NextContinuationToken,
 	}
 
 	// Установка заголовков
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	fmt.Println(response.Files)
 	// Сериализация ответа в JSON и отправка
 	err = json.NewEncoder(w).Encode(response)
 	if err != nil {
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"S3Storage/internal/clo"
+	"S3Storage/internal/config"
+)
+
+// Тарифы из конфигурации, задаются в Init. basic повторяет ограничения,
+// с которыми пользователи создавались до появления тарифов.
+var (
+	plans = map[string]config.Plan{
+		"basic": {
+			MaxBuckets:    10,
+			DefaultBucket: true,
+			BucketQuota:   config.Quota{MaxObjects: 10, MaxSize: 1000},
+			UserQuota:     config.Quota{MaxObjects: 10, MaxSize: 1000},
+		},
+	}
+	defaultPlan = "basic"
+)
+
+// lookupPlan возвращает тариф name или, если name пуст, тариф по умолчанию.
+func lookupPlan(name string) (string, config.Plan, bool) {
+	if name == "" {
+		name = defaultPlan
+	}
+	plan, ok := plans[name]
+	return name, plan, ok
+}
+
+func quotaLimits(q config.Quota) clo.QuotaLimits {
+	return clo.QuotaLimits{MaxObjects: q.MaxObjects, MaxSize: q.MaxSize}
+}
+
+// userPlan возвращает тариф пользователя login или пустую строку, если
+// тариф не записан.
+func userPlan(ctx context.Context, login string) (string, error) {
+	var plan sql.NullString
+	err := db.QueryRowContext(ctx, "SELECT plan FROM Person WHERE login = $1", login).Scan(&plan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return plan.String, err
+}
+
+// setUserPlan записывает тариф пользователя login; пустой plan
+// записывается как NULL. Запись в Person к этому моменту уже создана.
+func setUserPlan(ctx context.Context, login, plan string) error {
+	_, err := db.ExecContext(ctx, "UPDATE Person SET plan = $2 WHERE login = $1",
+		login, sql.NullString{String: plan, Valid: plan != ""})
+	return err
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"S3Storage/internal/config"
+	"S3Storage/internal/objectstore"
+)
+
+// newHTTPClient создаёт HTTP-клиент с общим для всех хранилищ пулом
+// соединений. Таймаута на весь запрос нет: скачивание больших файлов
+// может идти долго.
+func newHTTPClient(cfg config.Client) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          cfg.MaxIdleConns,
+			MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+			IdleConnTimeout:       cfg.IdleConnTimeout,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// storePool хранит клиенты хранилища пользователей CLO, чтобы не
+// создавать их на каждый запрос. Клиент привязан к ключу доступа: после
+// смены ключей создаётся новый.
+type storePool struct {
+	newStore    func(accessKey, secretKey string) (objectstore.Store, error)
+	idleTimeout time.Duration
+
+	mu     sync.Mutex
+	stores map[string]*pooledStore
+}
+
+type pooledStore struct {
+	store     objectstore.Store
+	accessKey string
+	lastUsed  time.Time
+}
+
+func newStorePool(idleTimeout time.Duration, newStore func(accessKey, secretKey string) (objectstore.Store, error)) *storePool {
+	return &storePool{
+		newStore:    newStore,
+		idleTimeout: idleTimeout,
+		stores:      make(map[string]*pooledStore),
+	}
+}
+
+// get возвращает клиент пользователя username с ключами accessKey и
+// secretKey, создавая его при необходимости.
+func (p *storePool) get(username, accessKey, secretKey string) (objectstore.Store, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ps, ok := p.stores[username]; ok && ps.accessKey == accessKey {
+		ps.lastUsed = time.Now()
+		return ps.store, nil
+	}
+	store, err := p.newStore(accessKey, secretKey)
+	if err != nil {
+		return nil, err
+	}
+	p.stores[username] = &pooledStore{store: store, accessKey: accessKey, lastUsed: time.Now()}
+	return store, nil
+}
+
+// invalidate удаляет клиент пользователя username. Запросы, которые уже
+// получили клиент, завершатся с ним.
+func (p *storePool) invalidate(username string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.stores, username)
+}
+
+// evictIdle удаляет клиенты, не использовавшиеся дольше idleTimeout.
+func (p *storePool) evictIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for username, ps := range p.stores {
+		if time.Since(ps.lastUsed) > p.idleTimeout {
+			delete(p.stores, username)
+		}
+	}
+}
+
+// runEviction периодически удаляет неиспользуемые клиенты. При
+// idleTimeout <= 0 клиенты живут до смены ключей.
+func (p *storePool) runEviction() {
+	if p.idleTimeout <= 0 {
+		return
+	}
+	interval := p.idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.evictIdle()
+	}
+}
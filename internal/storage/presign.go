@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"S3Storage/internal/auth"
+	"S3Storage/internal/objectstore"
+)
+
+// Настройки выдачи подписанных ссылок, задаются в Init.
+var (
+	presignDefaultTTL  = 15 * time.Minute
+	presignMaxTTL      = 7 * 24 * time.Hour
+	presignMaxPostSize = int64(5 << 30)
+)
+
+// PresignDownload выдаёт ссылку GET для скачивания файла напрямую из бакета.
+func PresignDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target, ok := presignRequest(w, r, auth.PermRead)
+	if !ok {
+		return
+	}
+
+	url, err := target.presigner.PresignGet(r.Context(), target.bucket, target.filename, target.expires)
+	if err != nil {
+		http.Error(w, "Ошибка подписи ссылки: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	writePresigned(w, map[string]interface{}{
+		"method":     http.MethodGet,
+		"url":        url,
+		"expires_at": time.Now().Add(target.expires).UTC(),
+	})
+}
+
+// PresignUpload выдаёт ссылку PUT для загрузки файла напрямую в бакет.
+// Если указан content_type, клиент должен отправить тот же Content-Type.
+// Размер загрузки по такой ссылке не ограничен, поэтому ссылка не
+// выдаётся, когда квота исчерпана или ожидаемый размер size в неё не
+// помещается.
+func PresignUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target, ok := presignRequest(w, r, auth.PermWrite)
+	if !ok {
+		return
+	}
+
+	var size int64
+	if v := r.FormValue("size"); v != "" {
+		var err error
+		if size, err = strconv.ParseInt(v, 10, 64); err != nil || size < 0 {
+			http.Error(w, "Некорректный параметр size", http.StatusBadRequest)
+			return
+		}
+	}
+	quota, err := checkQuota(r.Context(), target.store, target.username, target.bucket, target.filename, size)
+	if err == nil && quota.remaining == 0 {
+		err = errQuotaExceeded
+	}
+	if err != nil {
+		http.Error(w, "Ошибка проверки квоты: "+err.Error(), quotaErrorStatus(err))
+		return
+	}
+
+	contentType := r.FormValue("content_type")
+	url, err := target.presigner.PresignPut(r.Context(), target.bucket, target.filename, target.expires, objectstore.PutOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		http.Error(w, "Ошибка подписи ссылки: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	headers := map[string]string{}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	writePresigned(w, map[string]interface{}{
+		"method":     http.MethodPut,
+		"url":        url,
+		"headers":    headers,
+		"expires_at": time.Now().Add(target.expires).UTC(),
+	})
+}
+
+// PresignPost выдаёт политику браузерной загрузки через POST с
+// ограничениями размера (max_size, min_size) и типа содержимого
+// (content_type; значение с "/" на конце задаёт префикс). Размер
+// дополнительно ограничивается оставшейся квотой.
+func PresignPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	target, ok := presignRequest(w, r, auth.PermWrite)
+	if !ok {
+		return
+	}
+
+	cond := objectstore.PostConditions{
+		MaxSize:     presignMaxPostSize,
+		ContentType: r.FormValue("content_type"),
+	}
+	if v := r.FormValue("max_size"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size <= 0 || size > presignMaxPostSize {
+			http.Error(w, "Некорректный параметр max_size", http.StatusBadRequest)
+			return
+		}
+		cond.MaxSize = size
+	}
+	if v := r.FormValue("min_size"); v != "" {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || size < 0 || size > cond.MaxSize {
+			http.Error(w, "Некорректный параметр min_size", http.StatusBadRequest)
+			return
+		}
+		cond.MinSize = size
+	}
+
+	quota, err := checkQuota(r.Context(), target.store, target.username, target.bucket, target.filename, cond.MinSize)
+	if err == nil && quota.remaining == 0 {
+		err = errQuotaExceeded
+	}
+	if err != nil {
+		http.Error(w, "Ошибка проверки квоты: "+err.Error(), quotaErrorStatus(err))
+		return
+	}
+	if quota.remaining > 0 && quota.remaining < cond.MaxSize {
+		cond.MaxSize = quota.remaining
+	}
+
+	post, err := target.presigner.PresignPost(r.Context(), target.bucket, target.filename, target.expires, cond)
+	if err != nil {
+		http.Error(w, "Ошибка подписи политики: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	writePresigned(w, map[string]interface{}{
+		"method":     http.MethodPost,
+		"url":        post.URL,
+		"fields":     post.Fields,
+		"max_size":   cond.MaxSize,
+		"expires_at": time.Now().Add(target.expires).UTC(),
+	})
+}
+
+// presignTarget — объект, на который выдаётся подписанная ссылка.
+type presignTarget struct {
+	store     objectstore.Store
+	presigner objectstore.Presigner
+	username  string
+	bucket    string
+	filename  string
+	expires   time.Duration
+}
+
+// presignRequest проверяет параметры username, bucket, filename, expires
+// (в секундах) и право perm вызывающего и возвращает объект вместе с
+// хранилищем, умеющим подписывать ссылки.
+func presignRequest(w http.ResponseWriter, r *http.Request, perm auth.Permission) (*presignTarget, bool) {
+	username := requestUser(r, r.FormValue("username"))
+	filename := r.FormValue("filename")
+	if filename == "" {
+		http.Error(w, "Отсутствует параметр filename", http.StatusBadRequest)
+		return nil, false
+	}
+
+	expires := presignDefaultTTL
+	if v := r.FormValue("expires"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > presignMaxTTL {
+			http.Error(w, "Некорректный параметр expires", http.StatusBadRequest)
+			return nil, false
+		}
+		expires = time.Duration(seconds) * time.Second
+	}
+
+	bucket, ok := requestBucket(w, r, username, r.FormValue("bucket"))
+	if !ok {
+		return nil, false
+	}
+	if !authorizeObject(w, r, username, bucket, filename, perm) {
+		return nil, false
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return nil, false
+	}
+	presigner, ok := store.(objectstore.Presigner)
+	if !ok {
+		http.Error(w, "Хранилище не поддерживает подписанные ссылки", http.StatusNotImplemented)
+		return nil, false
+	}
+	return &presignTarget{
+		store:     store,
+		presigner: presigner,
+		username:  username,
+		bucket:    bucket,
+		filename:  filename,
+		expires:   expires,
+	}, true
+}
+
+func writePresigned(w http.ResponseWriter, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,472 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"S3Storage/internal/auth"
+	"S3Storage/internal/objectstore"
+)
+
+// Возобновляемая загрузка в стиле протокола tus поверх multipart upload:
+//
+//	POST   /uploads?filename=            создать загрузку (заголовок Upload-Length)
+//	HEAD   /uploads/{id}                 узнать текущее смещение (Upload-Offset)
+//	PATCH  /uploads/{id}                 дописать часть с позиции Upload-Offset
+//	POST   /uploads/{id}/complete        собрать объект из загруженных частей
+//	DELETE /uploads/{id}                 отменить загрузку
+//
+// Каждый PATCH становится одной частью multipart-загрузки, поэтому все
+// части, кроме последней, должны быть не меньше 5 МиБ и не больше
+// resumableMaxPart. Если PATCH оборвался, смещение не меняется и часть
+// отправляется заново.
+
+const tusVersion = "1.0.0"
+
+var (
+	uploadSessionTTL = 24 * time.Hour
+	// resumableMaxPart — наибольший размер тела PATCH. Хранилище
+	// буферизует каждую часть целиком, поэтому он равен размеру части
+	// потоковой загрузки (storage.upload.part_size_mb).
+	resumableMaxPart = int64(16 << 20)
+)
+
+// uploadPartLease — сколько действует отметка о передаче части. Если
+// запрос завис или сервис перезапустился, по истечении срока часть
+// можно отправить заново.
+const uploadPartLease = 15 * time.Minute
+
+func ResumableUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/uploads"), "/")
+	if rest == "" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			return
+		}
+		createResumableUpload(w, r)
+		return
+	}
+
+	id, action, _ := strings.Cut(rest, "/")
+	switch {
+	case action == "" && r.Method == http.MethodHead:
+		headResumableUpload(w, r, id)
+	case action == "" && r.Method == http.MethodPatch:
+		patchResumableUpload(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		abortResumableUpload(w, r, id)
+	case action == "complete" && r.Method == http.MethodPost:
+		completeResumableUpload(w, r, id)
+	case action == "" || action == "complete":
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func createResumableUpload(w http.ResponseWriter, r *http.Request) {
+	username := requestUser(r, r.URL.Query().Get("username"))
+	metadata := uploadMetadata(r.Header.Get("Upload-Metadata"))
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		filename = metadata["filename"]
+	}
+	if filename == "" {
+		http.Error(w, "Отсутствует параметр filename", http.StatusBadRequest)
+		return
+	}
+
+	totalSize, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || totalSize <= 0 {
+		http.Error(w, "Заголовок Upload-Length должен содержать положительный размер файла", http.StatusBadRequest)
+		return
+	}
+	if totalSize > resumableMaxPart*objectstore.MaxParts {
+		http.Error(w, "Файл слишком большой", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	acl, err := requestACL(r.URL.Query().Get("acl"), r.URL.Query().Get("visibility"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bucket, ok := requestBucket(w, r, username, r.URL.Query().Get("bucket"))
+	if !ok {
+		return
+	}
+	if !authorizeObject(w, r, username, bucket, filename, auth.PermWrite) {
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	if _, err := checkQuota(r.Context(), store, username, bucket, filename, totalSize); err != nil {
+		http.Error(w, "Ошибка проверки квоты: "+err.Error(), quotaErrorStatus(err))
+		return
+	}
+
+	// Содержимое ещё не получено: тип берётся из filetype метаданных или
+	// по расширению.
+	uploadID, err := store.CreateMultipartUpload(r.Context(), bucket, filename, objectstore.PutOptions{
+		ContentType: detectContentType(metadata["filetype"], filename, nil),
+		ACL:         acl,
+	})
+	if err != nil {
+		http.Error(w, "Ошибка создания загрузки: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	session := &uploadSession{
+		ID:         newUploadSessionID(),
+		Login:      username,
+		Bucket:     bucket,
+		Key:        filename,
+		S3UploadID: uploadID,
+		TotalSize:  totalSize,
+		ExpiresAt:  time.Now().Add(uploadSessionTTL),
+	}
+	if err := createUploadSession(r.Context(), session); err != nil {
+		store.AbortMultipartUpload(context.Background(), bucket, filename, uploadID)
+		http.Error(w, "Ошибка сохранения загрузки: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/uploads/"+session.ID)
+	w.Header().Set("Upload-Offset", "0")
+	w.Header().Set("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": session.ID})
+}
+
+func headResumableUpload(w http.ResponseWriter, r *http.Request, id string) {
+	session, ok := loadUploadSession(w, r, id)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(session.TotalSize, 10))
+	w.Header().Set("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+func patchResumableUpload(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type должен быть application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Некорректный заголовок Upload-Offset", http.StatusBadRequest)
+		return
+	}
+	size := r.ContentLength
+	if size <= 0 {
+		http.Error(w, "Требуется заголовок Content-Length", http.StatusLengthRequired)
+		return
+	}
+	if size > resumableMaxPart {
+		http.Error(w, fmt.Sprintf("Часть больше %d байт, разделите её на несколько PATCH", resumableMaxPart), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	session, ok := loadUploadSession(w, r, id)
+	if !ok {
+		return
+	}
+	store, err := openStore(r.Context(), session.Login)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	// Строка загрузки блокируется только на время проверок и отметки о
+	// передаче части: сама передача идёт вне транзакции.
+	session, partNumber, lease, ok := startUploadPart(w, r, id, offset, size)
+	if !ok {
+		return
+	}
+
+	body := &countingReader{r: http.MaxBytesReader(w, r.Body, size)}
+	etag, err := store.UploadPart(r.Context(), session.Bucket, session.Key, session.S3UploadID, partNumber, body, size)
+	if err != nil {
+		if err := releaseUploadPart(context.WithoutCancel(r.Context()), id, lease); err != nil {
+			log.Println("release upload part:", err)
+		}
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		writeUploadError(r.Context(), w, body, err)
+		return
+	}
+
+	// Часть уже в хранилище: запись о ней не должна пропасть из-за
+	// обрыва соединения клиента.
+	if err := finishUploadPart(context.WithoutCancel(r.Context()), id, lease, partNumber, etag, size); err != nil {
+		writeUploadSessionError(w, err)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset+size, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startUploadPart проверяет смещение и размер части в короткой
+// транзакции и отмечает загрузку id как занятую передачей части.
+// Возвращает загрузку, номер части и срок отметки.
+func startUploadPart(w http.ResponseWriter, r *http.Request, id string, offset, size int64) (*uploadSession, int, time.Time, bool) {
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, 0, time.Time{}, false
+	}
+	defer tx.Rollback()
+
+	session, err := lockUploadSession(r.Context(), tx, id)
+	if err != nil {
+		writeUploadSessionError(w, err)
+		return nil, 0, time.Time{}, false
+	}
+
+	if offset != session.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		http.Error(w, fmt.Sprintf("Смещение %d не совпадает с текущим %d", offset, session.Offset), http.StatusConflict)
+		return nil, 0, time.Time{}, false
+	}
+	last := offset+size == session.TotalSize
+	switch {
+	case offset+size > session.TotalSize:
+		http.Error(w, "Часть выходит за пределы Upload-Length", http.StatusBadRequest)
+		return nil, 0, time.Time{}, false
+	case !last && size < objectstore.MinPartSize:
+		http.Error(w, fmt.Sprintf("Часть меньше %d байт допустима только в конце файла", objectstore.MinPartSize), http.StatusBadRequest)
+		return nil, 0, time.Time{}, false
+	}
+
+	parts, err := listUploadParts(r.Context(), tx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, 0, time.Time{}, false
+	}
+	partNumber := len(parts) + 1
+	if partNumber > objectstore.MaxParts {
+		http.Error(w, "Превышено число частей загрузки", http.StatusBadRequest)
+		return nil, 0, time.Time{}, false
+	}
+
+	lease, err := leaseUploadPart(r.Context(), tx, id, uploadPartLease)
+	if err != nil {
+		writeUploadSessionError(w, err)
+		return nil, 0, time.Time{}, false
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, 0, time.Time{}, false
+	}
+	return session, partNumber, lease, true
+}
+
+func completeResumableUpload(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := loadUploadSession(w, r, id); !ok {
+		return
+	}
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	session, err := lockUploadSession(r.Context(), tx, id)
+	if err != nil {
+		writeUploadSessionError(w, err)
+		return
+	}
+	if session.Offset != session.TotalSize {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		http.Error(w, fmt.Sprintf("Загружено %d из %d байт", session.Offset, session.TotalSize), http.StatusConflict)
+		return
+	}
+
+	parts, err := listUploadParts(r.Context(), tx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	store, err := openStore(r.Context(), session.Login)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	// Объект мог появиться после создания загрузки, поэтому заменяемый
+	// объект определяется непосредственно перед завершением.
+	replaced, err := existingObject(r.Context(), store, session.Bucket, session.Key)
+	if err != nil {
+		http.Error(w, "Ошибка проверки файла: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	if err := store.CompleteMultipartUpload(r.Context(), session.Bucket, session.Key, session.S3UploadID, parts); err != nil {
+		http.Error(w, "Ошибка завершения загрузки: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+	recordWrite(r.Context(), session.Bucket, replaced, session.TotalSize)
+
+	if err := deleteUploadSession(r.Context(), tx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bucket": session.Bucket,
+		"key":    session.Key,
+		"size":   session.TotalSize,
+	})
+}
+
+func abortResumableUpload(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := loadUploadSession(w, r, id); !ok {
+		return
+	}
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	session, err := lockUploadSession(r.Context(), tx, id)
+	if err != nil {
+		writeUploadSessionError(w, err)
+		return
+	}
+
+	store, err := openStore(r.Context(), session.Login)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	err = store.AbortMultipartUpload(r.Context(), session.Bucket, session.Key, session.S3UploadID)
+	if err != nil && !errors.Is(err, objectstore.ErrUploadNotFound) {
+		http.Error(w, "Ошибка отмены загрузки: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	if err := deleteUploadSession(r.Context(), tx, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadUploadSession находит загрузку и проверяет право записи
+// вызывающего в её бакет.
+func loadUploadSession(w http.ResponseWriter, r *http.Request, id string) (*uploadSession, bool) {
+	session, err := getUploadSession(r.Context(), id)
+	if err != nil {
+		writeUploadSessionError(w, err)
+		return nil, false
+	}
+	if !authorizeObject(w, r, session.Login, session.Bucket, session.Key, auth.PermWrite) {
+		return nil, false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		http.Error(w, "Срок загрузки истёк", http.StatusGone)
+		return nil, false
+	}
+	return session, true
+}
+
+func writeUploadSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errUploadSessionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errUploadSessionBusy):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// uploadMetadata разбирает заголовок Upload-Metadata протокола tus:
+// пары "ключ значение-в-base64", разделённые запятыми.
+func uploadMetadata(header string) map[string]string {
+	meta := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		meta[key] = string(decoded)
+	}
+	return meta
+}
+
+func newUploadSessionID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// cleanupExpiredUploads периодически отменяет просроченные загрузки,
+// чтобы незавершённые части не занимали место в хранилище.
+func cleanupExpiredUploads(interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx := context.Background()
+		sessions, err := expiredUploadSessions(ctx)
+		if err != nil {
+			log.Println("cleanup uploads:", err)
+			continue
+		}
+		for _, s := range sessions {
+			store, err := openStore(ctx, s.Login)
+			if err != nil {
+				log.Println("cleanup uploads:", err)
+				continue
+			}
+			err = store.AbortMultipartUpload(ctx, s.Bucket, s.Key, s.S3UploadID)
+			if err != nil && !errors.Is(err, objectstore.ErrUploadNotFound) {
+				log.Println("cleanup uploads:", err)
+				continue
+			}
+			if err := deleteUploadSession(ctx, db, s.ID); err != nil {
+				log.Println("cleanup uploads:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Ограничения проверки паролей публичных ссылок: хеш считается дорого,
+// а /s/ доступен без токена.
+const (
+	// shareMaxFailures неверных паролей за shareFailureWindow закрывают
+	// ссылку для проверки пароля до конца окна.
+	shareMaxFailures   = 5
+	shareFailureWindow = time.Minute
+)
+
+var (
+	sharePasswordFailures = newFailureLimiter(shareMaxFailures, shareFailureWindow)
+
+	// sharePasswordSlots ограничивает число одновременно считаемых хешей.
+	sharePasswordSlots = make(chan struct{}, runtime.NumCPU())
+)
+
+// failureLimiter считает неудачные попытки по ключу в окне фиксированной
+// длины.
+type failureLimiter struct {
+	max    int
+	window time.Duration
+
+	mu       sync.Mutex
+	failures map[string]*failureWindow
+}
+
+type failureWindow struct {
+	start time.Time
+	count int
+}
+
+func newFailureLimiter(max int, window time.Duration) *failureLimiter {
+	return &failureLimiter{max: max, window: window, failures: make(map[string]*failureWindow)}
+}
+
+// blocked сообщает, исчерпаны ли попытки по ключу key, и через сколько
+// они восстановятся.
+func (l *failureLimiter) blocked(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[key]
+	if !ok {
+		return 0, false
+	}
+	left := l.window - time.Since(f.start)
+	if left <= 0 {
+		delete(l.failures, key)
+		return 0, false
+	}
+	return left, f.count >= l.max
+}
+
+// fail засчитывает неудачную попытку по ключу key.
+func (l *failureLimiter) fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.start) >= l.window {
+		f = &failureWindow{start: now}
+		l.failures[key] = f
+	}
+	f.count++
+
+	// Истёкшие окна удаляются, чтобы перебор ссылок не раздувал карту.
+	if len(l.failures) > 10000 {
+		for k, f := range l.failures {
+			if now.Sub(f.start) >= l.window {
+				delete(l.failures, k)
+			}
+		}
+	}
+}
+
+// checkSharePassword проверяет пароль ссылки, ограничивая число
+// одновременных проверок. Ошибка — только отмена ctx.
+func checkSharePassword(ctx context.Context, sh *share, password string) (bool, error) {
+	if sh.PasswordHash == nil {
+		return true, nil
+	}
+	select {
+	case sharePasswordSlots <- struct{}{}:
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	defer func() { <-sharePasswordSlots }()
+	return sh.checkPassword(password), nil
+}
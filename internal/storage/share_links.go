@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Настройки публичных ссылок, задаются в Init.
+var (
+	shareDefaultTTL = 24 * time.Hour
+	shareMaxTTL     = 30 * 24 * time.Hour
+	sharePublicURL  = "https://127.0.0.1:8443"
+)
+
+// shareAccessLimit — сколько последних обращений показывает GET /shares?id=.
+const shareAccessLimit = 100
+
+// Shares управляет публичными ссылками на файлы пользователя username:
+//
+//	POST   /shares         создать ссылку {"bucket", "filename", "expires_in", "max_downloads", "password"}
+//	GET    /shares         список ссылок
+//	GET    /shares?id=     ссылка и последние обращения к ней
+//	DELETE /shares?id=     отозвать ссылку
+//
+// По умолчанию username — сам вызывающий.
+func Shares(w http.ResponseWriter, r *http.Request) {
+	username := requestUser(r, r.URL.Query().Get("username"))
+
+	switch r.Method {
+	case http.MethodPost:
+		createShareLink(w, r, username)
+	case http.MethodGet:
+		if id := r.URL.Query().Get("id"); id != "" {
+			getShareLink(w, r, username, id)
+			return
+		}
+		listShareLinks(w, r, username)
+	case http.MethodDelete:
+		revokeShareLink(w, r, username)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+func createShareLink(w http.ResponseWriter, r *http.Request, username string) {
+	var req struct {
+		Bucket       string `json:"bucket"`
+		Filename     string `json:"filename"`
+		ExpiresIn    int64  `json:"expires_in"`
+		MaxDownloads *int   `json:"max_downloads"`
+		Password     string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Filename == "" || req.ExpiresIn < 0 || (req.MaxDownloads != nil && *req.MaxDownloads <= 0) {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	ttl := shareDefaultTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl > shareMaxTTL {
+		http.Error(w, "Некорректный параметр expires_in", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, username) {
+		return
+	}
+
+	bucket, ok := requestBucket(w, r, username, req.Bucket)
+	if !ok {
+		return
+	}
+
+	// Ссылка создаётся только на существующий объект.
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	if _, err := store.Head(r.Context(), bucket, req.Filename); err != nil {
+		http.Error(w, "Ошибка получения файла: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	id, err := newShareID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().UTC()
+	sh := &share{
+		ID:           id,
+		Owner:        username,
+		Bucket:       bucket,
+		Key:          req.Filename,
+		MaxDownloads: req.MaxDownloads,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	if req.Password != "" {
+		if err := sh.setPassword(req.Password); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := createShare(r.Context(), sh); err != nil {
+		http.Error(w, "Ошибка создания ссылки: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"share": sh,
+		"url":   sharePublicURL + "/s/" + sh.ID,
+	})
+}
+
+func listShareLinks(w http.ResponseWriter, r *http.Request, username string) {
+	if !authorize(w, r, username) {
+		return
+	}
+
+	shares, err := listShares(r.Context(), username)
+	if err != nil {
+		http.Error(w, "Ошибка получения ссылок: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"shares": shares})
+}
+
+func getShareLink(w http.ResponseWriter, r *http.Request, username, id string) {
+	if !authorize(w, r, username) {
+		return
+	}
+
+	sh, err := getShare(r.Context(), id)
+	if err == nil && sh.Owner != username {
+		err = errShareNotFound
+	}
+	if errors.Is(err, errShareNotFound) {
+		http.Error(w, "Ссылка не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка получения ссылки: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	accesses, err := listShareAccesses(r.Context(), id, shareAccessLimit)
+	if err != nil {
+		http.Error(w, "Ошибка получения обращений: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"share":    sh,
+		"accesses": accesses,
+	})
+}
+
+func revokeShareLink(w http.ResponseWriter, r *http.Request, username string) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Отсутствует параметр id", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, username) {
+		return
+	}
+
+	err := revokeShare(r.Context(), username, id)
+	if errors.Is(err, errShareNotFound) {
+		http.Error(w, "Ссылка не найдена", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка отзыва ссылки: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeShare отдаёт файл по публичной ссылке /s/{id} без токена. Пароль
+// ссылки передаётся через HTTP Basic (имя пользователя не проверяется).
+// Каждое обращение записывается в share_accesses.
+func ServeShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/s/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	status := serveShare(w, r, id)
+	if status == http.StatusNotFound {
+		return
+	}
+	// Клиент мог уже отключиться, но обращение всё равно записывается.
+	if err := recordShareAccess(context.WithoutCancel(r.Context()), id, r, status); err != nil {
+		log.Println("share access:", err)
+	}
+}
+
+func serveShare(w http.ResponseWriter, r *http.Request, id string) int {
+	sh, err := getShare(r.Context(), id)
+	if errors.Is(err, errShareNotFound) {
+		http.NotFound(w, r)
+		return http.StatusNotFound
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return http.StatusInternalServerError
+	}
+	if !sh.active() {
+		http.Error(w, "Ссылка больше не действует", http.StatusGone)
+		return http.StatusGone
+	}
+
+	if sh.HasPassword {
+		if wait, blocked := sharePasswordFailures.blocked(id); blocked {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			http.Error(w, "Слишком много неверных паролей, попробуйте позже", http.StatusTooManyRequests)
+			return http.StatusTooManyRequests
+		}
+		// Запрос без пароля — приглашение ввести его, хеш не считается и
+		// попытка не засчитывается.
+		_, password, _ := r.BasicAuth()
+		ok := false
+		if password != "" {
+			var err error
+			if ok, err = checkSharePassword(r.Context(), sh, password); err != nil {
+				http.Error(w, "Проверка пароля прервана: "+err.Error(), http.StatusRequestTimeout)
+				return http.StatusRequestTimeout
+			}
+			if !ok {
+				sharePasswordFailures.fail(id)
+			}
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="share", charset="UTF-8"`)
+			http.Error(w, "Требуется пароль", http.StatusUnauthorized)
+			return http.StatusUnauthorized
+		}
+	}
+
+	// Скачиванием считается каждый GET, на который отдано содержимое
+	// (200 или 206), в том числе докачка диапазона; HEAD лимит не
+	// расходует.
+	counted := r.Method == http.MethodGet
+	if counted {
+		ok, err := reserveShareDownload(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return http.StatusInternalServerError
+		}
+		if !ok {
+			http.Error(w, "Ссылка больше не действует", http.StatusGone)
+			return http.StatusGone
+		}
+	}
+
+	status := http.StatusInternalServerError
+	store, err := openStore(r.Context(), sh.Owner)
+	if err != nil {
+		status = storeErrorStatus(err)
+		http.Error(w, err.Error(), status)
+	} else {
+		status = serveObject(w, r, store, sh.Bucket, sh.Key)
+	}
+
+	if counted && status != http.StatusOK && status != http.StatusPartialContent {
+		if err := releaseShareDownload(context.WithoutCancel(r.Context()), id); err != nil {
+			log.Println("share release:", err)
+		}
+	}
+	return status
+}
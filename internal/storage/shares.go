@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var errShareNotFound = errors.New("ссылка не найдена")
+
+// sharePasswordIterations — число итераций PBKDF2 для паролей ссылок.
+const sharePasswordIterations = 100000
+
+// share — публичная ссылка на объект Key в бакете Bucket пользователя
+// Owner.
+type share struct {
+	ID           string     `json:"id"`
+	Owner        string     `json:"owner"`
+	Bucket       string     `json:"bucket"`
+	Key          string     `json:"key"`
+	PasswordSalt []byte     `json:"-"`
+	PasswordHash []byte     `json:"-"`
+	HasPassword  bool       `json:"has_password"`
+	MaxDownloads *int       `json:"max_downloads,omitempty"`
+	Downloads    int        `json:"downloads"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// shareAccess — запись об обращении к ссылке.
+type shareAccess struct {
+	AccessedAt time.Time `json:"accessed_at"`
+	RemoteAddr string    `json:"remote_addr"`
+	UserAgent  string    `json:"user_agent"`
+	Status     int       `json:"status"`
+}
+
+// active сообщает, что по ссылке ещё можно скачивать.
+func (s *share) active() bool {
+	if s.RevokedAt != nil || time.Now().After(s.ExpiresAt) {
+		return false
+	}
+	return s.MaxDownloads == nil || s.Downloads < *s.MaxDownloads
+}
+
+// checkPassword сравнивает пароль с сохранённым хешем. Ссылка без
+// пароля принимает любой.
+func (s *share) checkPassword(password string) bool {
+	if s.PasswordHash == nil {
+		return true
+	}
+	return hmac.Equal(hashSharePassword(s.PasswordSalt, password), s.PasswordHash)
+}
+
+// setPassword сохраняет солёный хеш пароля.
+func (s *share) setPassword(password string) error {
+	s.PasswordSalt = make([]byte, 16)
+	if _, err := rand.Read(s.PasswordSalt); err != nil {
+		return err
+	}
+	s.PasswordHash = hashSharePassword(s.PasswordSalt, password)
+	s.HasPassword = true
+	return nil
+}
+
+// hashSharePassword — PBKDF2-HMAC-SHA256 с длиной ключа в один блок.
+func hashSharePassword(salt []byte, password string) []byte {
+	return pbkdf2.Key([]byte(password), salt, sharePasswordIterations, sha256.Size, sha256.New)
+}
+
+func newShareID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+const shareColumns = "id, owner, bucket, object_key, password_salt, password_hash, max_downloads, downloads, created_at, expires_at, revoked_at"
+
+func scanShare(row interface{ Scan(...interface{}) error }) (*share, error) {
+	var s share
+	err := row.Scan(&s.ID, &s.Owner, &s.Bucket, &s.Key, &s.PasswordSalt, &s.PasswordHash,
+		&s.MaxDownloads, &s.Downloads, &s.CreatedAt, &s.ExpiresAt, &s.RevokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errShareNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.HasPassword = s.PasswordHash != nil
+	return &s, nil
+}
+
+func createShare(ctx context.Context, s *share) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO shares ("+shareColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		s.ID, s.Owner, s.Bucket, s.Key, s.PasswordSalt, s.PasswordHash,
+		s.MaxDownloads, s.Downloads, s.CreatedAt, s.ExpiresAt, s.RevokedAt)
+	return err
+}
+
+func getShare(ctx context.Context, id string) (*share, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+shareColumns+" FROM shares WHERE id = $1", id)
+	return scanShare(row)
+}
+
+func listShares(ctx context.Context, owner string) ([]*share, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+shareColumns+" FROM shares WHERE owner = $1 ORDER BY created_at", owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*share{}
+	for rows.Next() {
+		s, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
+
+func revokeShare(ctx context.Context, owner, id string) error {
+	res, err := db.ExecContext(ctx,
+		"UPDATE shares SET revoked_at = now() WHERE id = $1 AND owner = $2 AND revoked_at IS NULL", id, owner)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errShareNotFound
+	}
+	return nil
+}
+
+// reserveShareDownload засчитывает скачивание, если лимит ещё не
+// исчерпан. Проверка и увеличение счётчика выполняются одним запросом,
+// поэтому параллельные скачивания не превышают max_downloads.
+func reserveShareDownload(ctx context.Context, id string) (bool, error) {
+	res, err := db.ExecContext(ctx,
+		`UPDATE shares SET downloads = downloads + 1
+		 WHERE id = $1 AND revoked_at IS NULL AND expires_at > now()
+		   AND (max_downloads IS NULL OR downloads < max_downloads)`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// releaseShareDownload возвращает скачивание, которое не состоялось.
+func releaseShareDownload(ctx context.Context, id string) error {
+	_, err := db.ExecContext(ctx,
+		"UPDATE shares SET downloads = downloads - 1 WHERE id = $1 AND downloads > 0", id)
+	return err
+}
+
+func recordShareAccess(ctx context.Context, id string, r *http.Request, status int) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO share_accesses (share_id, remote_addr, user_agent, status) VALUES ($1, $2, $3, $4)",
+		id, r.RemoteAddr, r.UserAgent(), status)
+	return err
+}
+
+// listShareAccesses возвращает последние limit обращений к ссылке.
+func listShareAccesses(ctx context.Context, id string, limit int) ([]shareAccess, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT accessed_at, remote_addr, user_agent, status FROM share_accesses
+		 WHERE share_id = $1 ORDER BY accessed_at DESC LIMIT $2`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accesses := []shareAccess{}
+	for rows.Next() {
+		var a shareAccess
+		if err := rows.Scan(&a.AccessedAt, &a.RemoteAddr, &a.UserAgent, &a.Status); err != nil {
+			return nil, err
+		}
+		accesses = append(accesses, a)
+	}
+	return accesses, rows.Err()
+}
@@ -1,219 +1,211 @@
 package storage
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
+	"time"
 
-	"database/sql"
-
-	_ "github.com/lib/pq"
-	"github.com/spf13/viper"
+	"S3Storage/internal/auth"
+	"S3Storage/internal/cache"
+	"S3Storage/internal/clo"
+	"S3Storage/internal/config"
 )
 
-var bearerToken = os.Getenv("API_TOKEN")
-
-func GetKeys(name string) (string, string) {
-	userID, err := GetUserIdByName(name)
-	if err != nil {
-		fmt.Println(err)
-		return "", ""
-	}
-
-	url := "https://api.clo.ru/v2/s3/users/" + userID + "/credentials"
-
-	// Create a new GET request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", ""
-	}
-
-	// Set the headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", ""
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", ""
-	}
-
-	type Result struct {
-		AccessKey string `json:"access_key"`
-		SecretKey string `json:"secret_key"`
-	}
+var cloClient = clo.NewClient(os.Getenv("API_TOKEN"))
 
-	type Response struct {
-		Count  int      `json:"count"`
-		Result []Result `json:"result"`
-	}
+// cloKeys — ключи доступа пользователя S3.
+type cloKeys struct {
+	AccessKey string
+	SecretKey string
+}
 
-	var response Response
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", ""
-	}
+// Кэши ответов API CLO: проект, пользователи по имени и их ключи.
+// Пересоздаются в Init со сроком из clo.cache_ttl.
+var (
+	projectCache = cache.New[string](5 * time.Minute)
+	userCache    = cache.New[*clo.S3User](5 * time.Minute)
+	keysCache    = cache.New[cloKeys](5 * time.Minute)
+)
 
-	return response.Result[0].AccessKey, response.Result[0].SecretKey
+func initCLOCache(ttl time.Duration) {
+	projectCache = cache.New[string](ttl)
+	userCache = cache.New[*clo.S3User](ttl)
+	keysCache = cache.New[cloKeys](ttl)
 }
 
-func GetProjectId() (string, error) {
-	url := "https://api.clo.ru/v2/projects"
+func GetKeys(ctx context.Context, name string) (string, string, error) {
+	keys, err := keysCache.Get(ctx, name, func(ctx context.Context) (cloKeys, error) {
+		userID, err := GetUserIdByName(ctx, name)
+		if err != nil {
+			return cloKeys{}, err
+		}
 
-	// Create a new GET request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("error creating request: %v", err)
-	}
+		creds, err := cloClient.ListCredentials(ctx, userID)
+		if err != nil {
+			return cloKeys{}, err
+		}
+		newest, ok := newestCredentials(creds)
+		if !ok {
+			return cloKeys{}, fmt.Errorf("%w: %s", clo.ErrNoCredentials, name)
+		}
+		return cloKeys{AccessKey: newest.AccessKey, SecretKey: newest.SecretKey}, nil
+	})
+	return keys.AccessKey, keys.SecretKey, err
+}
 
-	// Set the headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
+func GetProjectId(ctx context.Context) (string, error) {
+	return projectCache.Get(ctx, "", cloClient.DefaultProjectID)
+}
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func GetUserIdByName(ctx context.Context, name string) (string, error) {
+	user, err := GetUser(ctx, name)
 	if err != nil {
-		return "", fmt.Errorf("error sending request: %v", err)
+		return "", err
 	}
-	defer resp.Body.Close()
+	return user.ID, nil
+}
 
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("error reading response body: %v", err)
-	}
+// GetUser возвращает пользователя CLO name вместе с его квотами. Значение
+// общее для всех вызывающих и не должно изменяться.
+func GetUser(ctx context.Context, name string) (*clo.S3User, error) {
+	return userCache.Get(ctx, name, func(ctx context.Context) (*clo.S3User, error) {
+		projectID, err := GetProjectId(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return cloClient.FindUser(ctx, projectID, name)
+	})
+}
 
-	type Instance struct {
-		StoppingReason *string `json:"stopping_reason"`
-		Name           string  `json:"name"`
-		Status         string  `json:"status"`
-		HasAbuse       bool    `json:"has_abuse"`
-		ID             string  `json:"id"`
-		CreatedIn      string  `json:"created_in"`
+// InvalidateCredentials забывает пользователя, ключи и клиент хранилища
+// пользователя name, например после его удаления или смены ключей.
+func InvalidateCredentials(name string) {
+	userCache.Invalidate(name)
+	keysCache.Invalidate(name)
+	if pool != nil {
+		pool.invalidate(name)
 	}
+}
 
-	type Response struct {
-		Result []Instance `json:"result"`
-		Count  int        `json:"count"`
-	}
+// PurgeCredentials очищает все кэши ответов API CLO. Клиенты хранилища
+// остаются: они привязаны к ключам и заменятся при их смене.
+func PurgeCredentials() {
+	projectCache.Purge()
+	userCache.Purge()
+	keysCache.Purge()
+}
 
-	// Parse the JSON response
-	var response Response
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("error unmarshalling response: %v", err)
+// CredentialsCache сбрасывает кэш ключей пользователя login или, если
+// login не указан, все кэши ответов API CLO. Доступен только
+// администратору.
+func CredentialsCache(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Check if we have results and return the first ID
-	if len(response.Result) > 0 {
-		return response.Result[0].ID, nil
+	if login := r.URL.Query().Get("login"); login != "" {
+		InvalidateCredentials(login)
+	} else {
+		PurgeCredentials()
 	}
-
-	return "", fmt.Errorf("no project found in response")
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func GetUserIdByName(projectName string) (string, error) {
-	projectID, err := GetProjectId()
-	if err != nil {
-		fmt.Println(err)
-	}
-	url := "https://api.clo.ru/v2/projects/" + projectID + "/s3/users"
-
-	// Create a new GET request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("error creating request: %v", err)
-	}
+var authenticator auth.Authenticator
 
-	// Set the headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+bearerToken)
+// initAuth собирает цепочку аутентификаторов: токены из базы и, если
+// включено, JWT провайдера OIDC.
+func initAuth(ctx context.Context, cfg config.Auth) error {
+	chain := auth.Chain{tokens}
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("error sending request: %v", err)
+	if cfg.JWT.Enabled {
+		source := cfg.JWT.JWKSFile
+		if cfg.JWT.JWKSURL != "" {
+			source = cfg.JWT.JWKSURL
+		}
+		if source == "" {
+			return errors.New("auth.jwt: jwks_url or jwks_file is required")
+		}
+		keys, err := auth.NewKeySet(ctx, source, cfg.JWT.RefreshInterval)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, auth.NewJWTAuthenticator(keys, auth.JWTConfig{
+			Issuer:     cfg.JWT.Issuer,
+			Audience:   cfg.JWT.Audience,
+			LoginClaim: cfg.JWT.LoginClaim,
+			ScopeClaim: cfg.JWT.ScopeClaim,
+			Leeway:     cfg.JWT.Leeway,
+		}, tokens.Role))
 	}
-	defer resp.Body.Close()
 
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("error reading response body: %v", err)
-	}
-	type Quota struct {
-		Type       string `json:"type"`
-		MaxObjects *int   `json:"max_objects"`
-		MaxSize    int    `json:"max_size"`
-	}
+	authenticator = chain
+	return nil
+}
 
-	type Project struct {
-		CanonicalName string  `json:"canonical_name"`
-		Tenant        *string `json:"tenant"`
-		MaxBuckets    int     `json:"max_buckets"`
-		Name          string  `json:"name"`
-		Quotas        []Quota `json:"quotas"`
-		Status        string  `json:"status"`
-		ID            string  `json:"id"`
-	}
+// Authenticator возвращает цепочку аутентификаторов для auth.Middleware.
+func Authenticator() auth.Authenticator {
+	return authenticator
+}
 
-	type ProjectResponse struct {
-		Result []Project `json:"result"`
-		Count  int       `json:"count"`
+// requestUser возвращает пользователя хранилища, к которому обращается
+// запрос: параметр username или, если он пуст, сам вызывающий.
+func requestUser(r *http.Request, username string) string {
+	if username != "" {
+		return username
 	}
-	// Parse the JSON response
-	var response ProjectResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("error unmarshalling response: %v", err)
-	}
-
-	// Loop through the results and find the project by name
-	for _, project := range response.Result {
-		if project.Name == projectName {
-			return project.ID, nil
-		}
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return principal.Login
 	}
-
-	return "", fmt.Errorf("project with name %s not found", projectName)
+	return ""
 }
 
-func CheckUser(login, token string) bool {
-	viper.AddConfigPath("configs")
-	viper.SetConfigName("config")
-	if err := viper.ReadInConfig(); err != nil {
-		panic(err)
+// authorize проверяет, что вызывающий — пользователь login или
+// администратор.
+func authorize(w http.ResponseWriter, r *http.Request, login string) bool {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return false
 	}
-
-	connect_db := "host=" + viper.GetString("db.host") + " " + "user=" + viper.GetString("db.username") + " " + "port=" + viper.GetString("db.port") + " " + "password=" + viper.GetString("db.password") + " " + "dbname=" + viper.GetString("db.dbname") + " " + "sslmode=" + viper.GetString("db.sslmode")
-	db, err := sql.Open("postgres", connect_db)
-	if err != nil {
-		panic(err)
+	if principal.Login != login && !principal.IsAdmin() {
+		http.Error(w, "Нет доступа к данным пользователя "+login, http.StatusForbidden)
+		return false
 	}
+	return true
+}
 
-	if err = db.Ping(); err != nil {
-		fmt.Println(err)
+// authorizeObject проверяет право perm вызывающего на ключ key в бакете
+// bucket пользователя owner. Владельцу и администратору доступно всё,
+// остальным — то, что владелец выдал через /grants. Для списков key —
+// запрошенный префикс.
+func authorizeObject(w http.ResponseWriter, r *http.Request, owner, bucket, key string, perm auth.Permission) bool {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
 		return false
 	}
 
-	var tok string
-	err = db.QueryRow("SELECT token FROM Person WHERE login = $1", login).Scan(&tok)
+	allowed, err := objectAllowed(r.Context(), principal, owner, bucket, key, perm)
 	if err != nil {
+		http.Error(w, "Ошибка проверки прав: "+err.Error(), http.StatusInternalServerError)
 		return false
 	}
-
-	if token == tok {
-		return true
-	} else {
+	if !allowed {
+		http.Error(w, "Нет доступа к данным пользователя "+owner, http.StatusForbidden)
 		return false
 	}
+	return true
+}
+
+// objectAllowed — проверка authorizeObject без ответа клиенту.
+func objectAllowed(ctx context.Context, principal *auth.Principal, owner, bucket, key string, perm auth.Permission) (bool, error) {
+	if principal.Login == owner || principal.IsAdmin() {
+		return true, nil
+	}
+	return tokens.Allowed(ctx, principal.Login, owner, bucket, key, perm)
 }
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"S3Storage/internal/auth"
+	"S3Storage/internal/config"
+	"S3Storage/internal/database"
+	"S3Storage/internal/objectstore"
+)
+
+// storeFunc возвращает хранилище объектов пользователя username.
+type storeFunc func(ctx context.Context, username string) (objectstore.Store, error)
+
+var (
+	backend   string
+	openStore storeFunc
+	pool      *storePool
+	db        *sql.DB
+	tokens    *auth.Store
+)
+
+// Init настраивает пакет по конфигурации сервиса. Вызывается один раз
+// при старте до регистрации обработчиков.
+func Init(cfg *config.Config) error {
+	var err error
+	db, err = database.Open(cfg.DB)
+	if err != nil {
+		return err
+	}
+	if err := database.Migrate(db); err != nil {
+		return err
+	}
+	tokens = auth.NewStore(db)
+	if err := initAuth(context.Background(), cfg.Auth); err != nil {
+		return err
+	}
+	initCLOCache(cfg.CLO.CacheTTL)
+	if cfg.Auth.TokenTTL > 0 {
+		defaultTokenTTL = cfg.Auth.TokenTTL
+	}
+
+	if len(cfg.Plans) > 0 {
+		plans = cfg.Plans
+	}
+	if cfg.DefaultPlan != "" {
+		defaultPlan = cfg.DefaultPlan
+	}
+	if _, _, ok := lookupPlan(""); !ok {
+		return fmt.Errorf("storage: default plan %q is not defined", defaultPlan)
+	}
+
+	sc := cfg.Storage
+	backend = sc.Backend
+	if err := initACL(sc.ACL); err != nil {
+		return err
+	}
+	if sc.Upload.ResumableTTL > 0 {
+		uploadSessionTTL = sc.Upload.ResumableTTL
+	}
+	if sc.Upload.PartSizeMB > 0 {
+		resumableMaxPart = max(sc.Upload.PartSizeMB<<20, objectstore.MinPartSize)
+	}
+	if sc.Presign.DefaultTTL > 0 {
+		presignDefaultTTL = sc.Presign.DefaultTTL
+	}
+	if sc.Presign.MaxTTL > 0 {
+		presignMaxTTL = sc.Presign.MaxTTL
+	}
+	if sc.Presign.MaxPostSizeMB > 0 {
+		presignMaxPostSize = sc.Presign.MaxPostSizeMB << 20
+	}
+	if sc.Usage.RecountInterval > 0 {
+		usageRecountInterval = sc.Usage.RecountInterval
+	}
+	if sc.Shares.DefaultTTL > 0 {
+		shareDefaultTTL = sc.Shares.DefaultTTL
+	}
+	if sc.Shares.MaxTTL > 0 {
+		shareMaxTTL = sc.Shares.MaxTTL
+	}
+	if sc.Shares.PublicURL != "" {
+		sharePublicURL = strings.TrimRight(sc.Shares.PublicURL, "/")
+	}
+
+	// Общие параметры клиентов S3; ключи подставляются для каждого
+	// хранилища отдельно.
+	s3Config := objectstore.S3Config{
+		Endpoint:       sc.Endpoint,
+		Region:         sc.Region,
+		ForcePathStyle: sc.ForcePathStyle,
+		PartSize:       sc.Upload.PartSizeMB << 20,
+		Concurrency:    sc.Upload.Concurrency,
+		HTTPClient:     newHTTPClient(sc.Client),
+		Retry: objectstore.RetryConfig{
+			MaxAttempts: sc.Client.MaxAttempts,
+			MaxBackoff:  sc.Client.MaxBackoff,
+		},
+	}
+	if sc.Client.LogRequests {
+		s3Config.APIOptions = append(s3Config.APIOptions, objectstore.RequestLogger(log.Default()))
+	}
+
+	switch sc.Backend {
+	case "clo":
+		pool = newStorePool(sc.Client.IdleTimeout, func(accessKey, secretKey string) (objectstore.Store, error) {
+			cfg := s3Config
+			cfg.AccessKey, cfg.SecretKey = accessKey, secretKey
+			return objectstore.NewS3(cfg)
+		})
+		go pool.runEviction()
+
+		openStore = func(ctx context.Context, username string) (objectstore.Store, error) {
+			accessKey, secretKey, err := GetKeys(ctx, username)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", errNoKeys, err)
+			}
+			return pool.get(username, accessKey, secretKey)
+		}
+	case "s3":
+		cfg := s3Config
+		cfg.AccessKey, cfg.SecretKey = sc.AccessKey, sc.SecretKey
+		store, err := objectstore.NewS3(cfg)
+		if err != nil {
+			return err
+		}
+		openStore = staticStore(store)
+	case "local":
+		store, err := objectstore.NewLocal(sc.LocalDir)
+		if err != nil {
+			return err
+		}
+		openStore = staticStore(store)
+	default:
+		return fmt.Errorf("storage: unknown backend %q", sc.Backend)
+	}
+
+	go cleanupExpiredUploads(time.Hour)
+	return nil
+}
+
+func staticStore(store objectstore.Store) storeFunc {
+	return func(context.Context, string) (objectstore.Store, error) {
+		return store, nil
+	}
+}
+
+var errNoKeys = errors.New("ошибка получения ключей")
+
+// defaultBucketSuffix — окончание имени бакета по умолчанию.
+const defaultBucketSuffix = "-default-bucket"
+
+func defaultBucket(username string) string {
+	return username + defaultBucketSuffix
+}
+
+// storeErrorStatus подбирает код ответа для ошибки хранилища.
+func storeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, objectstore.ErrNotFound), errors.Is(err, objectstore.ErrBucketNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, objectstore.ErrInvalidKey):
+		return http.StatusBadRequest
+	case errors.Is(err, objectstore.ErrBucketExists), errors.Is(err, objectstore.ErrBucketNotEmpty):
+		return http.StatusConflict
+	case errors.Is(err, errNoKeys):
+		return http.StatusBadGateway
+	case errors.Is(err, errFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errQuotaExceeded):
+		return http.StatusInsufficientStorage
+	}
+	return http.StatusInternalServerError
+}
+
+// requireCLO отвечает 501, если пользователи хранилища не управляются через API CLO.
+func requireCLO(w http.ResponseWriter) bool {
+	if backend != "clo" {
+		http.Error(w, "Управление пользователями доступно только для бэкенда clo", http.StatusNotImplemented)
+		return false
+	}
+	return true
+}
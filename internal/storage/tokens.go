@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"S3Storage/internal/auth"
+)
+
+// defaultTokenTTL — срок действия токена, если expires_in не указан.
+var defaultTokenTTL = 90 * 24 * time.Hour
+
+// Tokens управляет API-токенами пользователя username:
+//
+//	POST   /tokens            выпустить токен {"name", "expires_in", "scopes"}
+//	GET    /tokens            список токенов
+//	DELETE /tokens?id=        отозвать токен (all=true — все токены)
+//
+// По умолчанию username — сам вызывающий. Выпущенный токен не шире
+// токена, которым его выпустили.
+func Tokens(w http.ResponseWriter, r *http.Request) {
+	username := requestUser(r, r.URL.Query().Get("username"))
+
+	switch r.Method {
+	case http.MethodPost:
+		issueToken(w, r, username)
+	case http.MethodGet:
+		listTokens(w, r, username)
+	case http.MethodDelete:
+		revokeToken(w, r, username)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+func issueToken(w http.ResponseWriter, r *http.Request, username string) {
+	var req struct {
+		Name      string   `json:"name"`
+		ExpiresIn int64    `json:"expires_in"`
+		Scopes    []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" || req.ExpiresIn < 0 {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, username) {
+		return
+	}
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	// Без явного списка токен наследует scope вызывающего: иначе JWT с
+	// одним storage:tokens выпускал бы токен без ограничений.
+	scopes := principal.Scopes
+	if req.Scopes != nil {
+		for _, scope := range req.Scopes {
+			if !principal.HasScope(scope) {
+				http.Error(w, "Недостаточно прав: требуется "+scope, http.StatusForbidden)
+				return
+			}
+		}
+		scopes = req.Scopes
+	}
+
+	ttl := defaultTokenTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+
+	secret, tok, err := tokens.Issue(r.Context(), username, req.Name, ttl, scopes)
+	if errors.Is(err, auth.ErrTokenExists) {
+		http.Error(w, "Токен с таким именем уже есть", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка выпуска токена: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      secret,
+		"id":         tok.ID,
+		"name":       tok.Name,
+		"expires_at": tok.ExpiresAt,
+		"scopes":     tok.Scopes,
+	})
+}
+
+func listTokens(w http.ResponseWriter, r *http.Request, username string) {
+	if !authorize(w, r, username) {
+		return
+	}
+
+	list, err := tokens.List(r.Context(), username)
+	if err != nil {
+		http.Error(w, "Ошибка получения токенов: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tokens": list})
+}
+
+func revokeToken(w http.ResponseWriter, r *http.Request, username string) {
+	id := r.URL.Query().Get("id")
+	all := r.URL.Query().Get("all") == "true"
+	if id == "" && !all {
+		http.Error(w, "Отсутствует параметр id", http.StatusBadRequest)
+		return
+	}
+
+	if !authorize(w, r, username) {
+		return
+	}
+
+	if all {
+		n, err := tokens.RevokeAll(r.Context(), username)
+		if err != nil {
+			http.Error(w, "Ошибка отзыва токенов: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]interface{}{"revoked": n})
+		return
+	}
+
+	err := tokens.Revoke(r.Context(), username, id)
+	if errors.Is(err, auth.ErrTokenNotFound) {
+		http.Error(w, "Токен не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка отзыва токена: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// IssueToken выпускает токен без проверки прав. Используется командой
+// issue-token для выдачи первого токена пользователю.
+func IssueToken(ctx context.Context, login, name string) (string, error) {
+	secret, _, err := tokens.Issue(ctx, login, name, defaultTokenTTL, nil)
+	return secret, err
+}
@@ -1,90 +1,277 @@
 package storage
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"S3Storage/internal/auth"
+	"S3Storage/internal/objectstore"
 )
 
+// maxFieldSize ограничивает размер текстовых полей формы загрузки.
+const maxFieldSize = 4 << 10
+
 func UploadFileToS3(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Получение дополнительных данных
-	username := r.FormValue("username")
-	if username == "" {
-		http.Error(w, "Поле username отсутствует", http.StatusBadRequest)
+	// Тело читается потоком: поля формы должны идти перед полем file,
+	// а сам файл передаётся в хранилище частями без буферизации.
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Извлечение токена из заголовка Authorization
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "Authorization header is required", http.StatusUnauthorized)
+	fields, filePart, err := nextFilePart(mr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	defer filePart.Close()
 
-	// Проверка формата заголовка и извлечение токена
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+	// Получение дополнительных данных
+	username := fields.Get("username")
+	if username == "" {
+		username = requestUser(r, r.URL.Query().Get("username"))
+	}
+
+	bucketName, ok := requestBucket(w, r, username, formOrQuery(r, fields, "bucket"))
+	if !ok {
 		return
 	}
-	token := parts[1]
 
-	authOk := CheckUser(username, token)
-	if !authOk {
-		http.Error(w, "Failed to authentification", http.StatusInternalServerError)
+	if !authorizeObject(w, r, username, bucketName, filePart.FileName(), auth.PermWrite) {
 		return
 	}
 
-	// Чтение файла из формы данных
-	file, handler, err := r.FormFile("file")
+	metadata, tags, err := objectMetadata(fields)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	acl, err := requestACL(formOrQuery(r, fields, "acl"), formOrQuery(r, fields, "visibility"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	accessKey, secretKey := GetKeys(username)
-	if accessKey == "" || secretKey == "" {
-		http.Error(w, "Error getting keys", http.StatusBadRequest)
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
 		return
 	}
 
-	// Initialize a session using Amazon S3
-	sess, err := session.NewSession(&aws.Config{
-		Region:           aws.String("us-west-2"),
-		Credentials:      credentials.NewStaticCredentials(accessKey, secretKey, ""),
-		Endpoint:         aws.String("https://storage.clo.ru"),
-		S3ForcePathStyle: aws.Bool(true),
-	})
+	// Размер файла заранее неизвестен: квота проверяется перед загрузкой,
+	// а при превышении во время передачи загрузка прерывается.
+	quota, err := checkQuota(r.Context(), store, username, bucketName, filePart.FileName(), 0)
 	if err != nil {
-		http.Error(w, "Failed to create session", http.StatusBadRequest)
+		http.Error(w, "Ошибка проверки квоты: "+err.Error(), quotaErrorStatus(err))
+		return
+	}
+	// Тип содержимого: из заголовка части, по расширению или по первым
+	// байтам файла.
+	file := bufio.NewReaderSize(filePart, sniffLen)
+	head, err := file.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		http.Error(w, fmt.Sprintf("Загрузка прервана: ошибка чтения запроса: %v", err), http.StatusBadRequest)
 		return
 	}
+	contentType := detectContentType(filePart.Header.Get("Content-Type"), filePart.FileName(), head)
 
-	// Create S3 service client
-	svc := s3.New(sess)
+	var src io.Reader = file
+	if quota.remaining >= 0 {
+		src = &quotaReader{r: file, remaining: quota.remaining, capacity: quota.capacity}
+	}
 
-	// Upload the file to S3
-	_, err = svc.PutObject(&s3.PutObjectInput{
-		Bucket: aws.String(username + "-default-bucket"),
-		Key:    aws.String(handler.Filename),
-		Body:   file,
-		ACL:    aws.String("public-read"), // Adjust the ACL as per your requirement
+	// Загрузка файла в хранилище. При обрыве соединения контекст запроса
+	// отменяется, и незавершённая multipart-загрузка прерывается.
+	body := &countingReader{r: src}
+	err = store.Put(r.Context(), bucketName, filePart.FileName(), body, objectstore.PutOptions{
+		ContentType: contentType,
+		ACL:         acl,
+		Metadata:    metadata,
+		Tags:        tags,
 	})
 	if err != nil {
-		http.Error(w, "Failed to upload file", http.StatusBadRequest)
+		writeUploadError(r.Context(), w, body, err)
 		return
 	}
+	recordWrite(r.Context(), bucketName, quota.replaced, body.n)
 
 	fmt.Fprintf(w, "File uploaded successfully!\n")
 }
+
+// nextFilePart читает текстовые поля формы до первого поля file и
+// возвращает их вместе с частью, содержащей файл.
+func nextFilePart(mr *multipart.Reader) (multipartFields, *multipart.Part, error) {
+	fields := multipartFields{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, nil, errors.New("поле file отсутствует")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if part.FormName() == "file" {
+			if part.FileName() == "" {
+				part.Close()
+				return nil, nil, errors.New("у поля file не указано имя файла")
+			}
+			return fields, part, nil
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFieldSize+1))
+		part.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(value) > maxFieldSize {
+			return nil, nil, fmt.Errorf("поле %s слишком длинное", part.FormName())
+		}
+		fields[part.FormName()] = append(fields[part.FormName()], string(value))
+	}
+}
+
+// Ограничения S3 на теги и пользовательские метаданные объекта.
+const (
+	maxTags          = 10
+	maxTagKeyLen     = 128
+	maxTagValueLen   = 256
+	maxMetadataBytes = 2 << 10
+)
+
+// objectMetadata собирает из полей формы пользовательские метаданные
+// (поля x-amz-meta-<имя>) и теги (поле tagging в формате
+// "key1=value1&key2=value2", как заголовок x-amz-tagging).
+func objectMetadata(fields multipartFields) (map[string]string, map[string]string, error) {
+	var metadata map[string]string
+	size := 0
+	for name, values := range fields {
+		lower := strings.ToLower(name)
+		if !strings.HasPrefix(lower, "x-amz-meta-") {
+			continue
+		}
+		key := strings.TrimPrefix(lower, "x-amz-meta-")
+		if !validMetadataName(key) {
+			return nil, nil, fmt.Errorf("недопустимое имя метаданных %s", name)
+		}
+		value := values[0]
+		if !printableASCII(value) {
+			return nil, nil, fmt.Errorf("значение %s должно состоять из печатных символов ASCII", name)
+		}
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata[key] = value
+		size += len(key) + len(value)
+	}
+	if size > maxMetadataBytes {
+		return nil, nil, fmt.Errorf("метаданные объекта больше %d байт", maxMetadataBytes)
+	}
+
+	tagging := fields.Get("tagging")
+	if tagging == "" {
+		return metadata, nil, nil
+	}
+	values, err := url.ParseQuery(tagging)
+	if err != nil {
+		return nil, nil, fmt.Errorf("некорректное поле tagging: %v", err)
+	}
+	if len(values) > maxTags {
+		return nil, nil, fmt.Errorf("у объекта может быть не больше %d тегов", maxTags)
+	}
+	tags := make(map[string]string, len(values))
+	for k, v := range values {
+		if k == "" || len(k) > maxTagKeyLen || len(v) != 1 || len(v[0]) > maxTagValueLen {
+			return nil, nil, fmt.Errorf("некорректный тег %q: ключ до %d символов, одно значение до %d символов", k, maxTagKeyLen, maxTagValueLen)
+		}
+		tags[k] = v[0]
+	}
+	return metadata, tags, nil
+}
+
+// validMetadataName проверяет, что имя метаданных можно передать в
+// заголовке HTTP.
+func validMetadataName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func printableASCII(s string) bool {
+	for _, c := range s {
+		if c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type multipartFields map[string][]string
+
+// formOrQuery возвращает поле формы name или, если его нет, одноимённый
+// параметр запроса.
+func formOrQuery(r *http.Request, fields multipartFields, name string) string {
+	if v := fields.Get(name); v != "" {
+		return v
+	}
+	return r.URL.Query().Get(name)
+}
+
+func (f multipartFields) Get(name string) string {
+	if values := f[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// countingReader считает байты, полученные от клиента, и запоминает
+// ошибку чтения, чтобы отличить обрыв загрузки от ошибки хранилища.
+type countingReader struct {
+	r       io.Reader
+	n       int64
+	readErr error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err != nil && err != io.EOF {
+		c.readErr = err
+	}
+	return n, err
+}
+
+// writeUploadError сообщает, сколько байт было получено до ошибки и
+// на чьей стороне она произошла.
+func writeUploadError(ctx context.Context, w http.ResponseWriter, body *countingReader, err error) {
+	switch {
+	case ctx.Err() != nil:
+		// Клиент отключился — отвечать некому, загрузка уже прервана.
+		return
+	case errors.Is(body.readErr, errQuotaExceeded), errors.Is(body.readErr, errFileTooLarge):
+		http.Error(w, fmt.Sprintf("Загрузка прервана после %d байт: %v", body.n, body.readErr), storeErrorStatus(body.readErr))
+	case body.readErr != nil:
+		http.Error(w, fmt.Sprintf("Загрузка прервана после %d байт: ошибка чтения запроса: %v", body.n, body.readErr), http.StatusBadRequest)
+	default:
+		http.Error(w, fmt.Sprintf("Failed to upload file: получено %d байт, ошибка хранилища: %v", body.n, err), storeErrorStatus(err))
+	}
+}
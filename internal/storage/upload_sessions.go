@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"S3Storage/internal/objectstore"
+
+	"github.com/lib/pq"
+)
+
+var (
+	errUploadSessionNotFound = errors.New("загрузка не найдена")
+	errUploadSessionBusy     = errors.New("загрузка уже выполняется в другом запросе")
+)
+
+// uploadSession — возобновляемая загрузка, связанная с multipart-загрузкой
+// в хранилище.
+type uploadSession struct {
+	ID         string
+	Login      string
+	Bucket     string
+	Key        string
+	S3UploadID string
+	TotalSize  int64
+	Offset     int64
+	ExpiresAt  time.Time
+}
+
+// queryer — общее для *sql.DB и *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const uploadSessionColumns = "id, login, bucket, object_key, s3_upload_id, total_size, upload_offset, expires_at"
+
+func scanUploadSession(row interface{ Scan(...interface{}) error }) (*uploadSession, error) {
+	var s uploadSession
+	err := row.Scan(&s.ID, &s.Login, &s.Bucket, &s.Key, &s.S3UploadID, &s.TotalSize, &s.Offset, &s.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errUploadSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func createUploadSession(ctx context.Context, s *uploadSession) error {
+	_, err := db.ExecContext(ctx,
+		"INSERT INTO upload_sessions ("+uploadSessionColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		s.ID, s.Login, s.Bucket, s.Key, s.S3UploadID, s.TotalSize, s.Offset, s.ExpiresAt)
+	return err
+}
+
+func getUploadSession(ctx context.Context, id string) (*uploadSession, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+uploadSessionColumns+" FROM upload_sessions WHERE id = $1", id)
+	return scanUploadSession(row)
+}
+
+// lockUploadSession блокирует строку загрузки до конца транзакции tx.
+// Если строку уже держит другой запрос, возвращается errUploadSessionBusy.
+func lockUploadSession(ctx context.Context, tx *sql.Tx, id string) (*uploadSession, error) {
+	row := tx.QueryRowContext(ctx, "SELECT "+uploadSessionColumns+" FROM upload_sessions WHERE id = $1 FOR UPDATE NOWAIT", id)
+	s, err := scanUploadSession(row)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "55P03" {
+		return nil, errUploadSessionBusy
+	}
+	return s, err
+}
+
+func listUploadParts(ctx context.Context, q queryer, id string) ([]objectstore.CompletedPart, error) {
+	rows, err := q.QueryContext(ctx, "SELECT part_number, etag FROM upload_parts WHERE session_id = $1 ORDER BY part_number", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []objectstore.CompletedPart
+	for rows.Next() {
+		var p objectstore.CompletedPart
+		if err := rows.Scan(&p.PartNumber, &p.ETag); err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+// leaseUploadPart отмечает в транзакции tx, что часть загрузки id
+// передаётся в хранилище, и возвращает срок отметки: он же служит
+// идентификатором отметки в finishUploadPart и releaseUploadPart. Если
+// действующая отметка уже есть, возвращается errUploadSessionBusy.
+func leaseUploadPart(ctx context.Context, tx *sql.Tx, id string, lease time.Duration) (time.Time, error) {
+	var until time.Time
+	err := tx.QueryRowContext(ctx,
+		`UPDATE upload_sessions SET part_lease_until = now() + make_interval(secs => $2)
+		 WHERE id = $1 AND (part_lease_until IS NULL OR part_lease_until < now())
+		 RETURNING part_lease_until`, id, lease.Seconds()).Scan(&until)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, errUploadSessionBusy
+	}
+	return until, err
+}
+
+// finishUploadPart записывает загруженную часть, сдвигает смещение
+// загрузки и снимает отметку lease. Если отметка уже не действует
+// (её перехватил другой запрос или загрузку отменили), часть не
+// записывается и возвращается errUploadSessionBusy.
+func finishUploadPart(ctx context.Context, id string, lease time.Time, partNumber int, etag string, size int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE upload_sessions SET upload_offset = upload_offset + $3, part_lease_until = NULL, updated_at = now()
+		 WHERE id = $1 AND part_lease_until = $2`, id, lease, size)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errUploadSessionBusy
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO upload_parts (session_id, part_number, etag, size) VALUES ($1, $2, $3, $4)",
+		id, partNumber, etag, size)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// releaseUploadPart снимает отметку lease, если часть загрузить не удалось.
+func releaseUploadPart(ctx context.Context, id string, lease time.Time) error {
+	_, err := db.ExecContext(ctx,
+		"UPDATE upload_sessions SET part_lease_until = NULL WHERE id = $1 AND part_lease_until = $2", id, lease)
+	return err
+}
+
+func deleteUploadSession(ctx context.Context, q queryer, id string) error {
+	_, err := q.ExecContext(ctx, "DELETE FROM upload_sessions WHERE id = $1", id)
+	return err
+}
+
+func expiredUploadSessions(ctx context.Context) ([]*uploadSession, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+uploadSessionColumns+" FROM upload_sessions WHERE expires_at < now()")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*uploadSession
+	for rows.Next() {
+		s, err := scanUploadSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
@@ -0,0 +1,359 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"S3Storage/internal/clo"
+	"S3Storage/internal/config"
+	"S3Storage/internal/objectstore"
+)
+
+var (
+	errQuotaExceeded = errors.New("квота хранилища исчерпана")
+	errFileTooLarge  = errors.New("файл больше квоты хранилища")
+)
+
+// quotaLimit — ограничение числа объектов и их общего размера в байтах;
+// 0 — без ограничения.
+type quotaLimit struct {
+	MaxObjects int64 `json:"max_objects"`
+	MaxBytes   int64 `json:"max_bytes"`
+}
+
+// quota — ограничения пользователя целиком и каждого его бакета.
+type quota struct {
+	User   quotaLimit `json:"user"`
+	Bucket quotaLimit `json:"bucket"`
+}
+
+func (q *quota) unlimited() bool {
+	return q.User == quotaLimit{} && q.Bucket == quotaLimit{}
+}
+
+// bucketUsage — фактическое заполнение бакета.
+type bucketUsage struct {
+	Name    string `json:"name"`
+	Objects int64  `json:"objects"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// loadQuota возвращает квоты пользователя username: для бэкенда clo —
+// из API CLO через кэш пользователей, для остальных — из его тарифа в
+// конфигурации.
+func loadQuota(ctx context.Context, username string) (*quota, error) {
+	if backend != "clo" {
+		name, err := userPlan(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		_, plan, ok := lookupPlan(name)
+		if !ok {
+			_, plan, _ = lookupPlan("")
+		}
+		return &quota{User: planLimit(plan.UserQuota), Bucket: planLimit(plan.BucketQuota)}, nil
+	}
+
+	user, err := GetUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	q := &quota{}
+	for _, cq := range user.Quotas {
+		limit := quotaLimit{MaxBytes: int64(cq.MaxSize) * quotaSizeUnit}
+		if cq.MaxObjects != nil {
+			limit.MaxObjects = int64(*cq.MaxObjects)
+		}
+		switch cq.Type {
+		case clo.QuotaTypeUser:
+			q.User = limit
+		case clo.QuotaTypeBucket:
+			q.Bucket = limit
+		}
+	}
+	return q, nil
+}
+
+// quotaSizeUnit — единица max_size в тарифах и API CLO (мегабайт).
+const quotaSizeUnit = 1 << 20
+
+func planLimit(q config.Quota) quotaLimit {
+	return quotaLimit{MaxObjects: int64(q.MaxObjects), MaxBytes: int64(q.MaxSize) * quotaSizeUnit}
+}
+
+// usageRecountInterval — как часто счётчик бакета в bucket_usage
+// сверяется со списком объектов. Между пересчётами счётчик меняют
+// запись и удаление через сервис; загрузки по подписанным ссылкам
+// учитываются при следующем пересчёте.
+var usageRecountInterval = time.Hour
+
+// measureUsage возвращает заполнение всех бакетов пользователя
+// username по счётчикам в bucket_usage.
+func measureUsage(ctx context.Context, store objectstore.Store, username string) ([]bucketUsage, error) {
+	all, err := store.ListBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := ownedBuckets(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := []bucketUsage{}
+	for _, b := range all {
+		if !owned[b.Name] {
+			continue
+		}
+		u, err := countedUsage(ctx, store, b.Name)
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
+
+// countedUsage возвращает счётчик бакета bucket. Отсутствующий или
+// устаревший счётчик пересчитывается по списку объектов.
+func countedUsage(ctx context.Context, store objectstore.Store, bucket string) (bucketUsage, error) {
+	u := bucketUsage{Name: bucket}
+	var measuredAt time.Time
+	err := db.QueryRowContext(ctx,
+		`SELECT objects, bytes, measured_at FROM bucket_usage WHERE bucket = $1`, bucket).
+		Scan(&u.Objects, &u.Bytes, &measuredAt)
+	if err == nil && time.Since(measuredAt) < usageRecountInterval {
+		return u, nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return u, err
+	}
+	return recountUsage(ctx, store, bucket)
+}
+
+// recountUsage считает объекты и байты бакета по его списку и
+// сохраняет результат в bucket_usage.
+func recountUsage(ctx context.Context, store objectstore.Store, bucket string) (bucketUsage, error) {
+	list, err := objectstore.ListAll(ctx, store, bucket, objectstore.ListOptions{})
+	if err != nil {
+		return bucketUsage{}, err
+	}
+	u := bucketUsage{Name: bucket, Objects: int64(len(list.Objects))}
+	for _, obj := range list.Objects {
+		u.Bytes += obj.Size
+	}
+
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO bucket_usage (bucket, objects, bytes, measured_at) VALUES ($1, $2, $3, now())
+		 ON CONFLICT (bucket) DO UPDATE
+		 SET objects = EXCLUDED.objects, bytes = EXCLUDED.bytes, measured_at = EXCLUDED.measured_at`,
+		bucket, u.Objects, u.Bytes)
+	return u, err
+}
+
+// adjustUsage добавляет к счётчику бакета bucket objects объектов и
+// bytes байт. Бакет без счётчика пропускается: он будет посчитан при
+// следующей проверке квоты. Ошибка только записывается в журнал, так
+// как изменение в хранилище уже выполнено.
+func adjustUsage(ctx context.Context, bucket string, objects, bytes int64) {
+	_, err := db.ExecContext(context.WithoutCancel(ctx),
+		`UPDATE bucket_usage SET objects = GREATEST(objects + $2, 0), bytes = GREATEST(bytes + $3, 0)
+		 WHERE bucket = $1`, bucket, objects, bytes)
+	if err != nil {
+		log.Println("usage:", err)
+	}
+}
+
+// invalidateUsage помечает счётчик бакета устаревшим, если изменение
+// заполнения неизвестно заранее; бакет пересчитается при следующей
+// проверке квоты.
+func invalidateUsage(ctx context.Context, bucket string) {
+	_, err := db.ExecContext(context.WithoutCancel(ctx),
+		`UPDATE bucket_usage SET measured_at = '-infinity' WHERE bucket = $1`, bucket)
+	if err != nil {
+		log.Println("usage:", err)
+	}
+}
+
+// recordWrite учитывает запись объекта размером size, заменившую
+// объект replaced (nil — объекта не было).
+func recordWrite(ctx context.Context, bucket string, replaced *objectstore.ObjectInfo, size int64) {
+	if replaced != nil {
+		adjustUsage(ctx, bucket, 0, size-replaced.Size)
+		return
+	}
+	adjustUsage(ctx, bucket, 1, size)
+}
+
+// existingObject возвращает сведения об объекте key или nil, если его нет.
+func existingObject(ctx context.Context, store objectstore.Store, bucket, key string) (*objectstore.ObjectInfo, error) {
+	info, err := store.Head(ctx, bucket, key)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// quotaCheck — результат checkQuota.
+type quotaCheck struct {
+	// remaining — сколько байт ещё поместится, capacity — сколько
+	// вмещает квота целиком; -1 означает отсутствие ограничения.
+	remaining int64
+	capacity  int64
+	// replaced — существующий объект, который заменит запись, или nil.
+	replaced *objectstore.ObjectInfo
+}
+
+// checkQuota проверяет, что в бакет bucket пользователя username можно
+// записать объект key размером size. Замена существующего объекта не
+// увеличивает число объектов, а его размер освобождается. Файл больше
+// всей квоты — errFileTooLarge, больше оставшегося места —
+// errQuotaExceeded.
+func checkQuota(ctx context.Context, store objectstore.Store, username, bucket, key string, size int64) (*quotaCheck, error) {
+	replaced, err := existingObject(ctx, store, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	check := &quotaCheck{remaining: -1, capacity: -1, replaced: replaced}
+
+	q, err := loadQuota(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if q.unlimited() {
+		return check, nil
+	}
+	usage, err := measureUsage(ctx, store, username)
+	if err != nil {
+		return nil, err
+	}
+
+	var total, target bucketUsage
+	for _, u := range usage {
+		total.Objects += u.Objects
+		total.Bytes += u.Bytes
+		if u.Name == bucket {
+			target = u
+		}
+	}
+
+	added, freed := int64(1), int64(0)
+	if replaced != nil {
+		added, freed = 0, replaced.Size
+	}
+	for _, c := range []struct {
+		limit quotaLimit
+		used  bucketUsage
+	}{{q.User, total}, {q.Bucket, target}} {
+		if c.limit.MaxObjects > 0 && c.used.Objects+added > c.limit.MaxObjects {
+			return nil, errQuotaExceeded
+		}
+		if c.limit.MaxBytes > 0 {
+			check.remaining = minLimit(check.remaining, c.limit.MaxBytes-c.used.Bytes+freed)
+			check.capacity = minLimit(check.capacity, c.limit.MaxBytes)
+		}
+	}
+
+	switch {
+	case check.capacity >= 0 && size > check.capacity:
+		return nil, errFileTooLarge
+	case check.remaining >= 0 && size > check.remaining:
+		return nil, errQuotaExceeded
+	}
+	return check, nil
+}
+
+// minLimit возвращает меньшее из ограничений, где -1 — без ограничения.
+func minLimit(a, b int64) int64 {
+	if b < 0 {
+		b = 0
+	}
+	if a < 0 || b < a {
+		return b
+	}
+	return a
+}
+
+// quotaErrorStatus подбирает код ответа для ошибки checkQuota: 413 и
+// 507 для превышения квоты, 502 для ошибок API CLO.
+func quotaErrorStatus(err error) int {
+	var apiErr *clo.APIError
+	if errors.As(err, &apiErr) || clo.IsNotFound(err) {
+		return http.StatusBadGateway
+	}
+	return storeErrorStatus(err)
+}
+
+// quotaReader прерывает чтение, как только прочитано больше remaining
+// байт, чтобы потоковая загрузка не вышла за квоту.
+type quotaReader struct {
+	r         io.Reader
+	remaining int64
+	capacity  int64
+	n         int64
+}
+
+func (q *quotaReader) Read(p []byte) (int, error) {
+	n, err := q.r.Read(p)
+	q.n += int64(n)
+	if q.n > q.remaining {
+		if q.n > q.capacity {
+			return n, errFileTooLarge
+		}
+		return n, errQuotaExceeded
+	}
+	return n, err
+}
+
+// Usage возвращает квоты пользователя username и фактическое число
+// объектов и байт в его бакетах.
+func Usage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := requestUser(r, r.URL.Query().Get("username"))
+	if !authorize(w, r, username) {
+		return
+	}
+
+	store, err := openStore(r.Context(), username)
+	if err != nil {
+		http.Error(w, err.Error(), storeErrorStatus(err))
+		return
+	}
+	q, err := loadQuota(r.Context(), username)
+	if err != nil {
+		http.Error(w, "Ошибка получения квот: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	buckets, err := measureUsage(r.Context(), store, username)
+	if err != nil {
+		http.Error(w, "Ошибка подсчёта объектов: "+err.Error(), storeErrorStatus(err))
+		return
+	}
+
+	var total bucketUsage
+	for _, u := range buckets {
+		total.Objects += u.Objects
+		total.Bytes += u.Bytes
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"login":   username,
+		"quotas":  q,
+		"objects": total.Objects,
+		"bytes":   total.Bytes,
+		"buckets": buckets,
+	})
+}
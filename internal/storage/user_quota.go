@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"S3Storage/internal/clo"
+)
+
+// UserQuota показывает (GET ?login=) и меняет (PUT) квоты пользователя
+// в CLO. Доступен только администратору. PUT принимает либо тариф
+// {"login", "plan"}, либо отдельные квоты {"login", "user_quota",
+// "bucket_quota"}.
+func UserQuota(w http.ResponseWriter, r *http.Request) {
+	if !requireCLO(w) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		getUserQuota(w, r)
+	case http.MethodPut:
+		updateUserQuota(w, r)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+func getUserQuota(w http.ResponseWriter, r *http.Request) {
+	login := r.URL.Query().Get("login")
+	if login == "" {
+		http.Error(w, "Отсутствует параметр login", http.StatusBadRequest)
+		return
+	}
+	writeUserQuota(w, r, login)
+}
+
+// writeUserQuota отвечает тарифом и текущими квотами пользователя login.
+func writeUserQuota(w http.ResponseWriter, r *http.Request, login string) {
+	user, ok := findCLOUser(w, r, login)
+	if !ok {
+		return
+	}
+	plan, err := userPlan(r.Context(), login)
+	if err != nil {
+		http.Error(w, "Ошибка получения тарифа: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"login":       login,
+		"plan":        plan,
+		"max_buckets": user.MaxBuckets,
+		"quotas":      user.Quotas,
+	})
+}
+
+func updateUserQuota(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Login       string           `json:"login"`
+		Plan        string           `json:"plan"`
+		UserQuota   *clo.QuotaLimits `json:"user_quota"`
+		BucketQuota *clo.QuotaLimits `json:"bucket_quota"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Login == "" || (req.Plan == "") == (req.UserQuota == nil && req.BucketQuota == nil) {
+		http.Error(w, "Нужно указать либо plan, либо user_quota и/или bucket_quota", http.StatusBadRequest)
+		return
+	}
+
+	if req.Plan != "" {
+		_, plan, ok := lookupPlan(req.Plan)
+		if !ok {
+			http.Error(w, "Неизвестный тариф "+req.Plan, http.StatusBadRequest)
+			return
+		}
+		userQuota, bucketQuota := quotaLimits(plan.UserQuota), quotaLimits(plan.BucketQuota)
+		req.UserQuota, req.BucketQuota = &userQuota, &bucketQuota
+	}
+
+	user, ok := findCLOUser(w, r, req.Login)
+	if !ok {
+		return
+	}
+	// Квоты в кэше пользователя устаревают при любом исходе, в том числе
+	// после частичного изменения.
+	defer userCache.Invalidate(req.Login)
+
+	// CLO меняет квоты пользователя и бакетов отдельными запросами. Если
+	// второй запрос не прошёл, первая квота возвращается к прежнему
+	// значению, чтобы пользователь не остался с половиной изменений.
+	updates := []struct {
+		quotaType string
+		limits    *clo.QuotaLimits
+	}{
+		{clo.QuotaTypeUser, req.UserQuota},
+		{clo.QuotaTypeBucket, req.BucketQuota},
+	}
+	var applied []string
+	for _, u := range updates {
+		if u.limits == nil {
+			continue
+		}
+		err := cloClient.UpdateQuota(r.Context(), user.ID, clo.UpdateQuotaRequest{
+			Type:       u.quotaType,
+			MaxObjects: u.limits.MaxObjects,
+			MaxSize:    u.limits.MaxSize,
+		})
+		if err != nil {
+			msg := "Ошибка изменения квоты " + u.quotaType + ": " + err.Error()
+			for _, quotaType := range applied {
+				if err := restoreQuota(r.Context(), user, quotaType); err != nil {
+					msg += "; квота " + quotaType + " уже изменена, вернуть её не удалось: " + err.Error()
+				} else {
+					msg += "; квота " + quotaType + " возвращена к прежнему значению"
+				}
+			}
+			http.Error(w, msg, http.StatusBadGateway)
+			return
+		}
+		applied = append(applied, u.quotaType)
+	}
+
+	// Тариф, заданный явными квотами, больше не соответствует записанному.
+	if err := setUserPlan(r.Context(), req.Login, req.Plan); err != nil {
+		http.Error(w, "Ошибка сохранения тарифа: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeUserQuota(w, r, req.Login)
+}
+
+// restoreQuota возвращает квоте quotaType пользователя user значение,
+// прочитанное до изменения. Отсутствующая квота означает отсутствие
+// ограничений.
+func restoreQuota(ctx context.Context, user *clo.S3User, quotaType string) error {
+	req := clo.UpdateQuotaRequest{Type: quotaType}
+	for _, q := range user.Quotas {
+		if q.Type != quotaType {
+			continue
+		}
+		req.MaxSize = q.MaxSize
+		if q.MaxObjects != nil {
+			req.MaxObjects = *q.MaxObjects
+		}
+	}
+	return cloClient.UpdateQuota(context.WithoutCancel(ctx), user.ID, req)
+}
+
+// findCLOUser ищет пользователя login в CLO и отвечает 404 или 502,
+// если найти его не удалось.
+func findCLOUser(w http.ResponseWriter, r *http.Request, login string) (*clo.S3User, bool) {
+	projectID, err := GetProjectId(r.Context())
+	if err != nil {
+		http.Error(w, "Ошибка получения проекта: "+err.Error(), http.StatusBadGateway)
+		return nil, false
+	}
+	user, err := cloClient.FindUser(r.Context(), projectID, login)
+	if clo.IsNotFound(err) {
+		http.Error(w, "Пользователь не найден", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Ошибка поиска пользователя: "+err.Error(), http.StatusBadGateway)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"S3Storage/internal/auth"
+)
+
+// UserRole показывает (GET ?login=) и меняет (PUT {login, role}) роль
+// пользователя. Доступен только администратору.
+func UserRole(w http.ResponseWriter, r *http.Request) {
+	var login string
+	var role auth.Role
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		login = r.URL.Query().Get("login")
+		if login == "" {
+			http.Error(w, "Отсутствует параметр login", http.StatusBadRequest)
+			return
+		}
+		if role, err = tokens.Role(r.Context(), login); err != nil {
+			http.Error(w, "Ошибка получения роли: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case http.MethodPut:
+		var req struct {
+			Login string `json:"login"`
+			Role  string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Login == "" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		login = req.Login
+		if role, err = auth.ParseRole(req.Role); err != nil {
+			http.Error(w, "Неизвестная роль "+req.Role, http.StatusBadRequest)
+			return
+		}
+		if err := tokens.SetRole(r.Context(), login, role); err != nil {
+			http.Error(w, "Ошибка сохранения роли: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"login": login, "role": role})
+}
+
+// SetRole назначает роль без проверки прав. Используется командой
+// set-role для назначения первого администратора.
+func SetRole(ctx context.Context, login, role string) error {
+	parsed, err := auth.ParseRole(role)
+	if err != nil {
+		return err
+	}
+	return tokens.SetRole(ctx, login, parsed)
+}